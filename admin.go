@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/admin"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"gopkg.in/macaron.v1"
+)
+
+func setupAdmin(manager *downloads.Manager, m *macaron.Macaron, deleter admin.VersionDeleter) {
+	authHandler := setupAuthentication("ADMIN_AUTH")
+	admin.Create(manager, deleter).Setup(m, authHandler)
+}
@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/lib/pq"
+	"gopkg.in/macaron.v1"
+	"net/http"
+)
+
+const (
+	// PostgreSQL error code for unique constraint violations
+	uniqueViolation = "23505"
+)
+
+type Admin struct {
+	*downloads.Module
+
+	// deleter removes the files of deleted downloads from the Maven repository
+	deleter VersionDeleter
+}
+
+// VersionDeleter deletes a version using the function and removes its files
+// from the Maven repository, including the version in the Maven metadata.
+type VersionDeleter interface {
+	DeleteVersion(project maven.Identifier, version string, deleteIndexed func() error) error
+}
+
+type download struct {
+	id        int
+	projectID int
+	version   string
+
+	buildTypeID     int
+	allowsPromotion bool
+
+	label  sql.NullString
+	yanked bool
+}
+
+// Create creates the admin API. Downloads can be only deleted if the deleter is set.
+func Create(m *downloads.Manager, deleter VersionDeleter) *Admin {
+	return &Admin{Module: m.Module("Admin"), deleter: deleter}
+}
+
+func (a *Admin) Setup(m *macaron.Macaron, auth macaron.Handler) {
+	m.Group("/admin/:groupId/:artifactId/downloads/:version", func() {
+		m.Delete("/", a.Delete)
+
+		m.Post("/yank", a.Yank)
+		m.Delete("/yank", a.Unyank)
+
+		m.Put("/label", a.SetLabel)
+		m.Delete("/label", a.ClearLabel)
+
+		m.Put("/type", a.SetBuildType)
+	},
+		a.InitializeContext,
+		httperror.PreferProblem,
+		macaron.Recovery(),
+		auth,
+		downloads.ParseIdentifier)
+}
+
+func (a *Admin) Delete(ctx *macaron.Context, project maven.Identifier) error {
+	if a.deleter == nil {
+		return httperror.Problem(http.StatusNotImplemented, "indexer-required",
+			"Deleting downloads requires the indexer module", nil)
+	}
+
+	return a.deleter.DeleteVersion(project, ctx.Params("version"), func() error {
+		return a.update(ctx, project, func(tx *sql.Tx, d *download) error {
+			// Dependencies and artifacts are deleted by the foreign key constraints
+			_, err := tx.Exec("DELETE FROM downloads WHERE download_id = $1;", d.id)
+			if err != nil {
+				return httperror.InternalError("Database error (failed to delete download)", err)
+			}
+
+			downloads.RequestLogger(ctx).Println("Deleted", project.GroupID+":"+project.ArtifactID, d.version)
+			return nil
+		})
+	})
+}
+
+func (a *Admin) Yank(ctx *macaron.Context, project maven.Identifier) error {
+	return a.setYanked(ctx, project, true)
+}
+
+func (a *Admin) Unyank(ctx *macaron.Context, project maven.Identifier) error {
+	return a.setYanked(ctx, project, false)
+}
+
+func (a *Admin) setYanked(ctx *macaron.Context, project maven.Identifier, yanked bool) error {
+	return a.update(ctx, project, func(tx *sql.Tx, d *download) error {
+		if d.yanked == yanked {
+			return nil
+		}
+
+		_, err := tx.Exec("UPDATE downloads SET yanked = $1 WHERE download_id = $2;", yanked, d.id)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to update download)", err)
+		}
+
+		if yanked {
+			downloads.RequestLogger(ctx).Println("Yanked", project.GroupID+":"+project.ArtifactID, d.version)
+		} else {
+			downloads.RequestLogger(ctx).Println("Unyanked", project.GroupID+":"+project.ArtifactID, d.version)
+		}
+
+		return nil
+	})
+}
+
+type labelRequest struct {
+	Label string `json:"label"`
+}
+
+func (a *Admin) SetLabel(ctx *macaron.Context, project maven.Identifier) error {
+	var req labelRequest
+	err := readJSON(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	if req.Label == "" {
+		return httperror.BadRequest("Missing label", nil)
+	}
+
+	return a.setLabel(ctx, project, db.ToNullString(req.Label))
+}
+
+func (a *Admin) ClearLabel(ctx *macaron.Context, project maven.Identifier) error {
+	return a.setLabel(ctx, project, sql.NullString{})
+}
+
+func (a *Admin) setLabel(ctx *macaron.Context, project maven.Identifier, label sql.NullString) error {
+	return a.update(ctx, project, func(tx *sql.Tx, d *download) error {
+		if label.String == downloads.RecommendedLabel && !d.allowsPromotion {
+			return httperror.BadRequest("Build type does not allow promotion", nil)
+		}
+
+		_, err := tx.Exec("UPDATE downloads SET label = $1 WHERE download_id = $2;", label, d.id)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to update label)", err)
+		}
+
+		downloads.RequestLogger(ctx).Println("Changed label of", project.GroupID+":"+project.ArtifactID, d.version,
+			"from", nullString(d.label), "to", nullString(label))
+		return nil
+	})
+}
+
+type buildTypeRequest struct {
+	Type string `json:"type"`
+}
+
+func (a *Admin) SetBuildType(ctx *macaron.Context, project maven.Identifier) error {
+	var req buildTypeRequest
+	err := readJSON(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	if req.Type == "" {
+		return httperror.BadRequest("Missing build type", nil)
+	}
+
+	return a.update(ctx, project, func(tx *sql.Tx, d *download) error {
+		var buildTypeID int
+		var allowsPromotion bool
+		err := tx.QueryRow("SELECT build_type_id, allows_promotion FROM build_types "+
+			"JOIN project_build_types USING(build_type_id) "+
+			"WHERE project_id = $1 AND name = $2;",
+			d.projectID, req.Type).Scan(&buildTypeID, &allowsPromotion)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return httperror.Problem(http.StatusBadRequest, "unknown-build-type", "Unknown build type",
+					httperror.Details{"buildType": req.Type})
+			}
+			return httperror.InternalError("Database error (failed to lookup build type)", err)
+		}
+
+		if d.label.String == downloads.RecommendedLabel && !allowsPromotion {
+			return httperror.BadRequest("Build type does not allow promotion (remove the recommended label first)", nil)
+		}
+
+		_, err = tx.Exec("UPDATE downloads SET build_type_id = $1 WHERE download_id = $2;", buildTypeID, d.id)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+				return httperror.New(http.StatusConflict, "Build type already has a download with the same publish date", err)
+			}
+			return httperror.InternalError("Database error (failed to update build type)", err)
+		}
+
+		downloads.RequestLogger(ctx).Println("Changed build type of", project.GroupID+":"+project.ArtifactID, d.version, "to", req.Type)
+		return nil
+	})
+}
+
+// update looks up the requested download and runs the update function in a transaction. Afterwards, the
+// project is marked as updated and purged from the cache.
+func (a *Admin) update(ctx *macaron.Context, project maven.Identifier, f func(tx *sql.Tx, d *download) error) error {
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return httperror.InternalError("Database error (failed to start transaction)", err)
+	}
+
+	defer tx.Rollback()
+
+	d := &download{version: ctx.Params("version")}
+	err = tx.QueryRow("SELECT download_id, project_id, build_type_id, allows_promotion, label, yanked FROM downloads "+
+		"JOIN projects USING(project_id) JOIN build_types USING(build_type_id) "+
+		"WHERE group_id = $1 AND artifact_id = $2 AND version = $3 FOR UPDATE OF downloads;",
+		project.GroupID, project.ArtifactID, d.version).Scan(&d.id, &d.projectID, &d.buildTypeID,
+		&d.allowsPromotion, &d.label, &d.yanked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return httperror.Problem(http.StatusNotFound, "unknown-version", "Unknown version", nil)
+		}
+		return httperror.InternalError("Database error (failed to lookup download)", err)
+	}
+
+	err = f(tx, d)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE projects SET last_updated = current_timestamp WHERE project_id = $1;", d.projectID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update project timestamp)", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return httperror.InternalError("Database error (failed to commit transaction)", err)
+	}
+
+	if a.Cache != nil {
+		go a.Cache.PurgeProject(project)
+	}
+
+	return nil
+}
+
+func readJSON(ctx *macaron.Context, v interface{}) error {
+	err := json.NewDecoder(ctx.Req.Body().ReadCloser()).Decode(v)
+	if err != nil {
+		return httperror.BadRequest("Failed to parse request body", err)
+	}
+
+	return nil
+}
+
+func nullString(s sql.NullString) string {
+	if s.Valid {
+		return s.String
+	}
+	return "(none)"
+}
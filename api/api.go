@@ -4,9 +4,11 @@ import (
 	"github.com/SpongePowered/DownloadIndexer/downloads"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
 	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/SpongePowered/DownloadIndexer/metrics"
 	"github.com/go-macaron/gzip"
 	"gopkg.in/macaron.v1"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,16 +39,18 @@ func (a *API) Setup(m *macaron.Macaron, renderer macaron.Handler) {
 		m.Get("/", func(ctx *macaron.Context) {
 			ctx.Redirect(v1Docs)
 		})
-		m.Get("/projects", a.GetProjects)
+		m.Get("/projects", instrument("/projects"), a.GetProjects)
 
 		m.Group("/:groupId/:artifactId", func() {
-			m.Get("/", a.GetProject)
-			m.Get("/downloads", a.GetDownloads)
-			m.Get("/downloads/:version", a.GetDownload)
-			m.Get("/downloads/recommended", a.GetRecommendedDownload)
+			m.Get("/", instrument("/:groupId/:artifactId"), a.GetProject)
+			m.Get("/downloads", instrument("/:groupId/:artifactId/downloads"), a.GetDownloads)
+			m.Get("/downloads/:version", instrument("/:groupId/:artifactId/downloads/:version"), a.GetDownload)
+			m.Get("/downloads/recommended", instrument("/:groupId/:artifactId/downloads/recommended"),
+				a.GetRecommendedDownload)
 		}, a.parseIdentifier)
 	},
 		a.InitializeContext,
+		httperror.PreferProblem,
 		macaron.Recovery(),
 		gzip.Gziper(),
 		a.addHeaders,
@@ -69,6 +73,17 @@ func (a *API) addHeaders(resp http.ResponseWriter) {
 	}
 }
 
+// instrument reports the number of requests (by status code) and their latency for the route.
+func instrument(route string) macaron.Handler {
+	return func(ctx *macaron.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		metrics.APIRequestDuration.WithLabelValues(route).Observe(metrics.Since(start))
+		metrics.APIRequests.WithLabelValues(route, strconv.Itoa(ctx.Resp.Status())).Inc()
+	}
+}
+
 func parseIfModifiedSince(ctx *macaron.Context) (time.Time, error) {
 	return time.Parse(http.TimeFormat, ctx.Req.Header.Get("If-Modified-Since"))
 }
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
 	"github.com/SpongePowered/DownloadIndexer/maven"
 	"github.com/lib/pq"
@@ -13,8 +14,6 @@ import (
 	"time"
 )
 
-const recommendedLabel = "recommended"
-
 var (
 	emptyArray [0]struct{}
 )
@@ -26,9 +25,19 @@ type download struct {
 	Type            string    `json:"type"`
 	Commit          string    `json:"commit"`
 	Label           *string   `json:"label,omitempty"`
+	Yanked          bool      `json:"yanked,omitempty"`
+
+	Dependencies      map[string]string    `json:"dependencies,omitempty"`
+	MavenDependencies []*mavenDependency   `json:"mavenDependencies,omitempty"`
+	Artifacts         map[string]*artifact `json:"artifacts"`
+	Variants          map[string]*variant  `json:"variants,omitempty"`
+
+	// Project metadata from the POM
+	Description *string         `json:"description,omitempty"`
+	Licenses    json.RawMessage `json:"licenses,omitempty"`
+	SCM         json.RawMessage `json:"scm,omitempty"`
 
-	Dependencies map[string]string    `json:"dependencies,omitempty"`
-	Artifacts    map[string]*artifact `json:"artifacts"`
+	Plugin *plugin `json:"plugin,omitempty"`
 
 	Changelog json.RawMessage `json:"changelog,omitempty"`
 }
@@ -36,9 +45,49 @@ type download struct {
 type artifact struct {
 	URL string `json:"url"`
 
-	Size int    `json:"size"`
-	SHA1 string `json:"sha1"`
-	MD5  string `json:"md5"`
+	Size   int64   `json:"size"`
+	SHA1   string  `json:"sha1"`
+	MD5    string  `json:"md5"`
+	SHA256 *string `json:"sha256,omitempty"`
+	SHA512 *string `json:"sha512,omitempty"`
+}
+
+// plugin is the plugin metadata from the main JAR of the download
+type plugin struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	URL         *string  `json:"url,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+
+	Dependencies map[string]*pluginDependency `json:"dependencies,omitempty"`
+}
+
+type pluginDependency struct {
+	Version   string  `json:"version"`
+	Required  *bool   `json:"required,omitempty"`
+	LoadOrder *string `json:"loadOrder,omitempty"`
+}
+
+// mavenDependency is a dependency declared in the POM of the download
+type mavenDependency struct {
+	GroupID    string  `json:"groupId"`
+	ArtifactID string  `json:"artifactId"`
+	Version    *string `json:"version,omitempty"`
+	Classifier string  `json:"classifier,omitempty"`
+	Type       string  `json:"type"`
+	Scope      string  `json:"scope"`
+	Optional   bool    `json:"optional,omitempty"`
+}
+
+// variant is a variant from the Gradle module metadata of the download
+type variant struct {
+	Attributes   json.RawMessage `json:"attributes"`
+	Capabilities json.RawMessage `json:"capabilities,omitempty"`
+	Dependencies json.RawMessage `json:"dependencies,omitempty"`
+	// DependencyConstraints are the constraints for the versions of transitive dependencies
+	DependencyConstraints json.RawMessage `json:"dependencyConstraints,omitempty"`
+	Files                 json.RawMessage `json:"files,omitempty"`
+	AvailableAt           json.RawMessage `json:"availableAt,omitempty"`
 }
 
 func (a *API) GetDownload(ctx *macaron.Context, project maven.Identifier) error {
@@ -56,7 +105,7 @@ func (a *API) GetDownload(ctx *macaron.Context, project maven.Identifier) error
 	}
 
 	if dls == nil {
-		return httperror.NotFound("Unknown version")
+		return httperror.Problem(http.StatusNotFound, "unknown-version", "Unknown version", nil)
 	}
 
 	ctx.JSON(http.StatusOK, dls[0])
@@ -64,13 +113,13 @@ func (a *API) GetDownload(ctx *macaron.Context, project maven.Identifier) error
 }
 
 func (a *API) GetRecommendedDownload(ctx *macaron.Context, project maven.Identifier) error {
-	dls, err := a.filterDownloads(ctx, project, false, recommendedLabel)
+	dls, err := a.filterDownloads(ctx, project, false, downloads.RecommendedLabel)
 	if err != nil {
 		return err
 	}
 
 	if dls == nil {
-		return httperror.NotFound("No recommended version found")
+		return httperror.Problem(http.StatusNotFound, "no-recommended-version", "No recommended version found", nil)
 	}
 
 	ctx.JSON(http.StatusOK, dls[0])
@@ -112,7 +161,7 @@ func (a *API) createDownloadQuery(ctx *macaron.Context, project maven.Identifier
 		project.GroupID, project.ArtifactID).Scan(&q.projectID, &q.useSemVer, &lastUpdated)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, httperror.NotFound("Unknown project")
+			return nil, httperror.Problem(http.StatusNotFound, "unknown-project", "Unknown project", nil)
 		}
 		return nil, httperror.InternalError("Database error (failed to lookup project)", err)
 	}
@@ -131,7 +180,8 @@ func (a *API) createDownloadQuery(ctx *macaron.Context, project maven.Identifier
 }
 
 func (q *downloadQuery) init(dependencies bool) {
-	q.builder.Append("SELECT download_id, build_types.name, downloads.version, snapshot_version, published, commit, label")
+	q.builder.Append("SELECT download_id, build_types.name, downloads.version, snapshot_version, published, commit, " +
+		"label, yanked, description, licenses, scm, plugin_name, plugin_description, plugin_url, plugin_authors")
 
 	if q.changelog {
 		q.builder.Append(", changelog")
@@ -163,7 +213,7 @@ func (a *API) filterDownloads(ctx *macaron.Context, project maven.Identifier, ex
 	// Get possible dependencies
 	rows, err := a.DB.Query("SELECT DISTINCT name FROM dependencies "+
 		"JOIN downloads USING (download_id)"+
-		"WHERE project_id = $1;", q.projectID)
+		"WHERE project_id = $1 AND NOT yanked;", q.projectID)
 	if err != nil {
 		return nil, httperror.InternalError("Database error (failed to lookup dependencies)", err)
 	}
@@ -204,6 +254,9 @@ func (a *API) filterDownloads(ctx *macaron.Context, project maven.Identifier, ex
 
 	q.init(dependencies != nil)
 
+	// Yanked downloads can be only requested directly
+	q.builder.Append(" AND NOT yanked")
+
 	if buildType != "" {
 		q.builder.Parameter(" AND build_types.name = ", buildType)
 	}
@@ -270,19 +323,29 @@ func (q *downloadQuery) Read(a *API, project maven.Identifier) ([]*download, err
 	for rows.Next() {
 		var id int
 		dl := &download{Dependencies: make(map[string]string), Artifacts: make(map[string]*artifact)}
-		var changelogJSON []byte
+		var licensesJSON, scmJSON, changelogJSON []byte
+		p := new(plugin)
 
 		if q.changelog {
 			err = rows.Scan(&id, &dl.Type, &dl.Version, &dl.snapshotVersion, &dl.Published, &dl.Commit, &dl.Label,
-				&changelogJSON)
+				&dl.Yanked, &dl.Description, &licensesJSON, &scmJSON, &p.Name, &p.Description, &p.URL,
+				pq.Array(&p.Authors), &changelogJSON)
 		} else {
-			err = rows.Scan(&id, &dl.Type, &dl.Version, &dl.snapshotVersion, &dl.Published, &dl.Commit, &dl.Label)
+			err = rows.Scan(&id, &dl.Type, &dl.Version, &dl.snapshotVersion, &dl.Published, &dl.Commit, &dl.Label,
+				&dl.Yanked, &dl.Description, &licensesJSON, &scmJSON, &p.Name, &p.Description, &p.URL,
+				pq.Array(&p.Authors))
 		}
 
 		if err != nil {
 			return nil, httperror.InternalError("Database error (failed to read downloads)", err)
 		}
 
+		if p.Name != nil || p.Description != nil || p.URL != nil || p.Authors != nil {
+			dl.Plugin = p
+		}
+
+		dl.Licenses = json.RawMessage(licensesJSON)
+		dl.SCM = json.RawMessage(scmJSON)
 		dl.Changelog = json.RawMessage(changelogJSON)
 
 		downloadIDs = append(downloadIDs, int64(id))
@@ -296,7 +359,7 @@ func (q *downloadQuery) Read(a *API, project maven.Identifier) ([]*download, err
 	}
 
 	// Get download dependencies
-	rows, err = a.DB.Query("SELECT download_id, name, version FROM dependencies "+
+	rows, err = a.DB.Query("SELECT download_id, name, version, required, load_order FROM dependencies "+
 		"WHERE download_id = ANY($1);", pq.Array(downloadIDs))
 	if err != nil {
 		return nil, httperror.InternalError("Database error (failed to lookup dependencies)", err)
@@ -304,18 +367,85 @@ func (q *downloadQuery) Read(a *API, project maven.Identifier) ([]*download, err
 
 	for rows.Next() {
 		var downloadID int
-		var name, version string
-		err = rows.Scan(&downloadID, &name, &version)
+		var name string
+		dep := new(pluginDependency)
+		err = rows.Scan(&downloadID, &name, &dep.Version, &dep.Required, &dep.LoadOrder)
 		if err != nil {
 			return nil, httperror.InternalError("Database error (failed to read dependencies)", err)
 		}
 
-		downloadsMap[downloadID].Dependencies[name] = version
+		dl := downloadsMap[downloadID]
+		dl.Dependencies[name] = dep.Version
+
+		// Dependencies indexed before the plugin metadata was stored don't have additional information
+		if dep.Required != nil || dep.LoadOrder != nil {
+			if dl.Plugin == nil {
+				dl.Plugin = new(plugin)
+			}
+			if dl.Plugin.Dependencies == nil {
+				dl.Plugin.Dependencies = make(map[string]*pluginDependency)
+			}
+
+			dl.Plugin.Dependencies[name] = dep
+		}
+	}
+
+	// Get Maven dependencies (from the POM)
+	rows, err = a.DB.Query("SELECT download_id, group_id, artifact_id, version, classifier, type, scope, optional "+
+		"FROM maven_dependencies WHERE download_id = ANY($1) ORDER BY group_id, artifact_id;", pq.Array(downloadIDs))
+	if err != nil {
+		return nil, httperror.InternalError("Database error (failed to lookup Maven dependencies)", err)
 	}
 
-	// Get download artifacts
-	rows, err = a.DB.Query("SELECT download_id, classifier, extension, size, sha1, md5 FROM artifacts "+
-		"WHERE download_id = ANY($1);", pq.Array(downloadIDs))
+	for rows.Next() {
+		var downloadID int
+		dep := new(mavenDependency)
+		err = rows.Scan(&downloadID, &dep.GroupID, &dep.ArtifactID, &dep.Version, &dep.Classifier, &dep.Type,
+			&dep.Scope, &dep.Optional)
+		if err != nil {
+			return nil, httperror.InternalError("Database error (failed to read Maven dependencies)", err)
+		}
+
+		dl := downloadsMap[downloadID]
+		dl.MavenDependencies = append(dl.MavenDependencies, dep)
+	}
+
+	// Get download variants (from the Gradle module metadata)
+	rows, err = a.DB.Query("SELECT download_id, name, attributes, capabilities, dependencies, "+
+		"dependency_constraints, files, available_at FROM variants WHERE download_id = ANY($1);", pq.Array(downloadIDs))
+	if err != nil {
+		return nil, httperror.InternalError("Database error (failed to lookup variants)", err)
+	}
+
+	for rows.Next() {
+		var downloadID int
+		var name string
+		var attributes, capabilities, dependencies, dependencyConstraints, files, availableAt []byte
+		err = rows.Scan(&downloadID, &name, &attributes, &capabilities, &dependencies,
+			&dependencyConstraints, &files, &availableAt)
+		if err != nil {
+			return nil, httperror.InternalError("Database error (failed to read variants)", err)
+		}
+
+		dl := downloadsMap[downloadID]
+		if dl.Variants == nil {
+			dl.Variants = make(map[string]*variant)
+		}
+
+		dl.Variants[name] = &variant{
+			Attributes:   json.RawMessage(attributes),
+			Capabilities: json.RawMessage(capabilities),
+			Dependencies: json.RawMessage(dependencies),
+
+			DependencyConstraints: json.RawMessage(dependencyConstraints),
+			Files:                 json.RawMessage(files),
+			AvailableAt:           json.RawMessage(availableAt),
+		}
+	}
+
+	// Get download artifacts (only JARs, other indexed files like the module metadata are exposed separately)
+	rows, err = a.DB.Query("SELECT download_id, classifier, extension, size, sha1, md5, sha256, sha512 FROM artifacts "+
+		"WHERE download_id = ANY($1) AND extension = 'jar';", pq.Array(downloadIDs))
 	if err != nil {
 		return nil, httperror.InternalError("Database error (failed to lookup artifacts)", err)
 	}
@@ -327,7 +457,8 @@ func (q *downloadQuery) Read(a *API, project maven.Identifier) ([]*download, err
 		artifact := new(artifact)
 		var classifier, extension string
 
-		err = rows.Scan(&downloadID, &classifier, &extension, &artifact.Size, &artifact.SHA1, &artifact.MD5)
+		err = rows.Scan(&downloadID, &classifier, &extension, &artifact.Size, &artifact.SHA1, &artifact.MD5,
+			&artifact.SHA256, &artifact.SHA512)
 		if err != nil {
 			return nil, httperror.InternalError("Database error (failed to read artifacts)", err)
 		}
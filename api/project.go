@@ -52,7 +52,7 @@ func (a *API) GetProject(ctx *macaron.Context, c maven.Identifier) error {
 		&p.Name, &p.PluginID, &p.GitHub.Owner, &p.GitHub.Repo, &useSemVer, &lastUpdated)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return httperror.NotFound("Unknown project")
+			return httperror.Problem(http.StatusNotFound, "unknown-project", "Unknown project", nil)
 		}
 		return httperror.InternalError("Database error (failed to lookup project)", err)
 	}
@@ -89,7 +89,7 @@ func (a *API) GetProject(ctx *macaron.Context, c maven.Identifier) error {
 	rows, err = a.DB.Query("SELECT build_type_id, label, download_id, version FROM downloads "+
 		"WHERE (build_type_id, coalesce(label, ''), published) IN ("+
 		"SELECT build_type_id, coalesce(label, ''), MAX(published) FROM downloads "+
-		"WHERE project_id = $1 GROUP BY build_type_id, label)"+
+		"WHERE project_id = $1 AND NOT yanked GROUP BY build_type_id, label)"+
 		"ORDER BY published DESC;", projectID)
 	if err != nil {
 		return httperror.InternalError("Database error (failed to get latest downloads)", err)
@@ -136,7 +136,8 @@ rows:
 	}
 
 	// Get dependencies for latest builds
-	rows, err = a.DB.Query("SELECT * FROM dependencies WHERE download_ID = ANY($1);", pq.Array(downloadIDs))
+	rows, err = a.DB.Query("SELECT download_id, name, version FROM dependencies WHERE download_id = ANY($1);",
+		pq.Array(downloadIDs))
 	if err != nil {
 		return httperror.InternalError("Database error (failed to get latest dependencies)", err)
 	}
@@ -161,7 +162,7 @@ rows:
 
 	// Get all available dependencies
 	rows, err = a.DB.Query("SELECT DISTINCT name, split_part(dependencies.version, '-', 1) FROM dependencies "+
-		"JOIN downloads USING(download_id) WHERE project_id = $1;", projectID)
+		"JOIN downloads USING(download_id) WHERE project_id = $1 AND NOT yanked;", projectID)
 	if err != nil {
 		return httperror.InternalError("Database error (failed to lookup dependency versions)", err)
 	}
@@ -183,7 +184,7 @@ rows:
 	if useSemVer {
 		// Add all versions
 		rows, err = a.DB.Query("SELECT DISTINCT split_part(version, '-', 1) FROM downloads "+
-			"WHERE project_id = $1;", projectID)
+			"WHERE project_id = $1 AND NOT yanked;", projectID)
 		if err != nil {
 			return httperror.InternalError("Database error (failed to lookup versions)", err)
 		}
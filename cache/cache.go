@@ -3,6 +3,7 @@ package cache
 import (
 	"errors"
 	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/SpongePowered/DownloadIndexer/metrics"
 	"gopkg.in/macaron.v1"
 	"log"
 	"net/http"
@@ -30,10 +31,36 @@ func Create(logger *log.Logger, config string) (Cache, error) {
 	cacheType := config[:pos]
 	config = config[pos+1:]
 
+	var c Cache
+	var err error
+
 	switch cacheType {
 	case "fastly":
-		return parseFastly(logger, config)
+		c, err = parseFastly(logger, config)
 	default:
 		return nil, errors.New("Unsupported cache type: " + cacheType)
 	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return instrumentedCache{c}, nil
+}
+
+// instrumentedCache reports the results of the cache purges.
+type instrumentedCache struct {
+	Cache
+}
+
+func (c instrumentedCache) PurgeAll() bool {
+	ok := c.Cache.PurgeAll()
+	metrics.CachePurges.WithLabelValues("all", metrics.SuccessResult(ok)).Inc()
+	return ok
+}
+
+func (c instrumentedCache) PurgeProject(project maven.Identifier) bool {
+	ok := c.Cache.PurgeProject(project)
+	metrics.CachePurges.WithLabelValues("project", metrics.SuccessResult(ok)).Inc()
+	return ok
 }
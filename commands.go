@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type command struct {
+	usage string
+	// run executes the command and returns false if the arguments are invalid
+	run func(args []string) bool
+}
+
+var commands = map[string]*command{
+	"import":   {"import [flags] <groupId:artifactId>", importCommand},
+	"migrate":  {"migrate up|status", migrateCommand},
+	"projects": {"projects diff|apply <file>", projectsCommand},
+}
+
+func runCommand(args []string) {
+	c := commands[args[0]]
+	if c == nil || !c.run(args[1:]) {
+		printUsage()
+		os.Exit(2)
+	}
+}
+
+func printUsage() {
+	usages := make([]string, 0, len(commands))
+	for _, c := range commands {
+		usages = append(usages, c.usage)
+	}
+
+	sort.Strings(usages)
+	fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "[command]")
+	fmt.Fprintln(os.Stderr, "Available commands:\n ", strings.Join(usages, "\n  "))
+}
+
+func migrateCommand(args []string) bool {
+	if len(args) != 1 || (args[0] != "up" && args[0] != "status") {
+		return false
+	}
+
+	postgresDB := connectDatabase()
+	defer postgresDB.Close()
+
+	switch args[0] {
+	case "up":
+		migrateDatabase(postgresDB)
+	case "status":
+		status, err := db.MigrationStatus(postgresDB)
+		if err != nil {
+			logger.Fatalln("Failed to read migration status:", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tAPPLIED")
+		for _, m := range status {
+			applied := "pending"
+			if !m.Pending() {
+				applied = m.Applied.String()
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Description, applied)
+		}
+		w.Flush()
+	}
+
+	return true
+}
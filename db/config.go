@@ -0,0 +1,369 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+// configLock is the key of the PostgreSQL advisory lock that is held while reconciling
+// the project configuration, so multiple instances don't try to apply it at the same time.
+const configLock = 0x50524f4a // "PROJ"
+
+// defaultRecommendation is the recommendation policy of projects that don't
+// configure one (same as the default of the database column).
+const defaultRecommendation = "never"
+
+type Config struct {
+	BuildTypes []*BuildTypeConfig `yaml:"buildTypes"`
+	Projects   []*ProjectConfig   `yaml:"projects"`
+}
+
+type BuildTypeConfig struct {
+	Name            string `yaml:"name"`
+	AllowsPromotion bool   `yaml:"allowsPromotion"`
+}
+
+type ProjectConfig struct {
+	Name string `yaml:"name"`
+
+	GroupID    string `yaml:"groupId"`
+	ArtifactID string `yaml:"artifactId"`
+
+	PluginID string `yaml:"pluginId"`
+
+	GitHub struct {
+		Owner string `yaml:"owner"`
+		Repo  string `yaml:"repo"`
+	} `yaml:"github"`
+
+	UseSnapshots bool `yaml:"useSnapshots"`
+	UseSemVer    bool `yaml:"useSemVer"`
+
+	// Recommendation is the policy used to recommend new builds, see package recommend
+	Recommendation string `yaml:"recommendation"`
+
+	BuildTypes []string `yaml:"buildTypes"`
+}
+
+// LoadConfig loads and validates the configuration. The recommendation policies
+// of the projects are checked using validateRecommendation.
+func LoadConfig(path string, validateRecommendation func(policy string) error) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(Config)
+	err = yaml.UnmarshalStrict(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, c.validate(validateRecommendation)
+}
+
+func (c *Config) validate(validateRecommendation func(policy string) error) error {
+	buildTypes := make(map[string]bool)
+	for _, bt := range c.BuildTypes {
+		if bt.Name == "" {
+			return errors.New("Build type is missing a name")
+		}
+		if buildTypes[bt.Name] {
+			return errors.New("Duplicate build type: " + bt.Name)
+		}
+
+		buildTypes[bt.Name] = true
+	}
+
+	projects := make(map[string]bool)
+	for _, p := range c.Projects {
+		switch {
+		case p.Name == "":
+			return errors.New("Project is missing a name")
+		case projects[p.Name]:
+			return errors.New("Duplicate project: " + p.Name)
+		case p.GroupID == "" || p.ArtifactID == "":
+			return errors.New("Project " + p.Name + " is missing group or artifact ID")
+		case p.GitHub.Owner == "" || p.GitHub.Repo == "":
+			return errors.New("Project " + p.Name + " is missing the GitHub repository")
+		}
+
+		projects[p.Name] = true
+
+		if p.Recommendation == "" {
+			p.Recommendation = defaultRecommendation
+		}
+
+		if err := validateRecommendation(p.Recommendation); err != nil {
+			return errors.New("Project " + p.Name + " has an invalid recommendation policy: " + err.Error())
+		}
+
+		for _, bt := range p.BuildTypes {
+			if !buildTypes[bt] {
+				return errors.New("Project " + p.Name + " uses unknown build type: " + bt)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reconcile compares the configuration with the database and returns the
+// changes that are necessary to update the database. The changes are only
+// committed if apply is true. Projects and build types that are missing in the
+// configuration are reported, but never deleted, because that would also
+// delete all of their downloads.
+func Reconcile(db *sql.DB, c *Config, apply bool) (changes []string, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec("SELECT pg_advisory_xact_lock($1);", configLock)
+	if err != nil {
+		return nil, err
+	}
+
+	buildTypes, err := reconcileBuildTypes(tx, c, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = reconcileProjects(tx, c, buildTypes, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if apply {
+		err = tx.Commit()
+	}
+
+	return changes, err
+}
+
+func reconcileBuildTypes(tx *sql.Tx, c *Config, changes *[]string) (map[string]int, error) {
+	type buildType struct {
+		id              int
+		allowsPromotion bool
+	}
+
+	rows, err := tx.Query("SELECT build_type_id, name, allows_promotion FROM build_types;")
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*buildType)
+	for rows.Next() {
+		var name string
+		bt := new(buildType)
+		err = rows.Scan(&bt.id, &name, &bt.allowsPromotion)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		existing[name] = bt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int)
+
+	for _, config := range c.BuildTypes {
+		bt := existing[config.Name]
+		delete(existing, config.Name)
+
+		if bt == nil {
+			var id int
+			err = tx.QueryRow("INSERT INTO build_types VALUES (DEFAULT, $1, $2) RETURNING build_type_id;",
+				config.Name, config.AllowsPromotion).Scan(&id)
+			if err != nil {
+				return nil, err
+			}
+
+			*changes = append(*changes, fmt.Sprintf("+ build type %s (allowsPromotion: %t)",
+				config.Name, config.AllowsPromotion))
+			result[config.Name] = id
+			continue
+		}
+
+		if bt.allowsPromotion != config.AllowsPromotion {
+			_, err = tx.Exec("UPDATE build_types SET allows_promotion = $1 WHERE build_type_id = $2;",
+				config.AllowsPromotion, bt.id)
+			if err != nil {
+				return nil, err
+			}
+
+			*changes = append(*changes, fmt.Sprintf("~ build type %s: allowsPromotion %t -> %t",
+				config.Name, bt.allowsPromotion, config.AllowsPromotion))
+		}
+
+		result[config.Name] = bt.id
+	}
+
+	for name := range existing {
+		*changes = append(*changes, "! build type "+name+" is not configured (not removed)")
+	}
+
+	return result, nil
+}
+
+func reconcileProjects(tx *sql.Tx, c *Config, buildTypeIDs map[string]int, changes *[]string) error {
+	type project struct {
+		id     int
+		config ProjectConfig
+	}
+
+	rows, err := tx.Query("SELECT project_id, name, group_id, artifact_id, plugin_id, github_owner, github_repo, " +
+		"use_snapshots, use_semver, recommendation FROM projects;")
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*project)
+	for rows.Next() {
+		p := new(project)
+		var pluginID sql.NullString
+
+		err = rows.Scan(&p.id, &p.config.Name, &p.config.GroupID, &p.config.ArtifactID, &pluginID,
+			&p.config.GitHub.Owner, &p.config.GitHub.Repo, &p.config.UseSnapshots, &p.config.UseSemVer,
+			&p.config.Recommendation)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		p.config.PluginID = pluginID.String
+		existing[p.config.Name] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, config := range c.Projects {
+		p := existing[config.Name]
+		delete(existing, config.Name)
+
+		var projectID int
+		if p == nil {
+			err = tx.QueryRow("INSERT INTO projects (name, group_id, artifact_id, plugin_id, github_owner, github_repo, "+
+				"use_snapshots, use_semver, recommendation) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) "+
+				"RETURNING project_id;",
+				config.Name, config.GroupID, config.ArtifactID, ToNullString(config.PluginID),
+				config.GitHub.Owner, config.GitHub.Repo, config.UseSnapshots, config.UseSemVer,
+				config.Recommendation).Scan(&projectID)
+			if err != nil {
+				return err
+			}
+
+			*changes = append(*changes, fmt.Sprintf("+ project %s (%s:%s)", config.Name, config.GroupID,
+				config.ArtifactID))
+		} else {
+			projectID = p.id
+
+			diff := diffProject(&p.config, config)
+			if diff != nil {
+				_, err = tx.Exec("UPDATE projects SET group_id = $1, artifact_id = $2, plugin_id = $3, "+
+					"github_owner = $4, github_repo = $5, use_snapshots = $6, use_semver = $7, recommendation = $8, "+
+					"last_updated = current_timestamp WHERE project_id = $9;",
+					config.GroupID, config.ArtifactID, ToNullString(config.PluginID), config.GitHub.Owner,
+					config.GitHub.Repo, config.UseSnapshots, config.UseSemVer, config.Recommendation, projectID)
+				if err != nil {
+					return err
+				}
+
+				*changes = append(*changes, "~ project "+config.Name+": "+strings.Join(diff, ", "))
+			}
+		}
+
+		err = reconcileProjectBuildTypes(tx, config, projectID, buildTypeIDs, changes)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name := range existing {
+		*changes = append(*changes, "! project "+name+" is not configured (not removed)")
+	}
+
+	return nil
+}
+
+func diffProject(old, updated *ProjectConfig) (diff []string) {
+	add := func(field string, a, b interface{}) {
+		if a != b {
+			diff = append(diff, fmt.Sprintf("%s %v -> %v", field, a, b))
+		}
+	}
+
+	add("groupId", old.GroupID, updated.GroupID)
+	add("artifactId", old.ArtifactID, updated.ArtifactID)
+	add("pluginId", old.PluginID, updated.PluginID)
+	add("github.owner", old.GitHub.Owner, updated.GitHub.Owner)
+	add("github.repo", old.GitHub.Repo, updated.GitHub.Repo)
+	add("useSnapshots", old.UseSnapshots, updated.UseSnapshots)
+	add("useSemVer", old.UseSemVer, updated.UseSemVer)
+	add("recommendation", old.Recommendation, updated.Recommendation)
+	return
+}
+
+func reconcileProjectBuildTypes(tx *sql.Tx, config *ProjectConfig, projectID int, buildTypeIDs map[string]int,
+	changes *[]string) error {
+
+	rows, err := tx.Query("SELECT name FROM project_build_types JOIN build_types USING(build_type_id) "+
+		"WHERE project_id = $1;", projectID)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		existing[name] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range config.BuildTypes {
+		if existing[name] {
+			delete(existing, name)
+			continue
+		}
+
+		_, err = tx.Exec("INSERT INTO project_build_types VALUES ($1, $2);", projectID, buildTypeIDs[name])
+		if err != nil {
+			return err
+		}
+
+		*changes = append(*changes, "+ project "+config.Name+": build type "+name)
+	}
+
+	for name := range existing {
+		_, err = tx.Exec("DELETE FROM project_build_types WHERE project_id = $1 AND build_type_id = "+
+			"(SELECT build_type_id FROM build_types WHERE name = $2);", projectID, name)
+		if err != nil {
+			return err
+		}
+
+		*changes = append(*changes, "- project "+config.Name+": build type "+name)
+	}
+
+	return nil
+}
@@ -8,19 +8,17 @@ import (
 )
 
 func ConnectPostgres(url string) (*sql.DB, error) {
-	return sql.Open("postgres", url)
-}
-
-func Reset(db *sql.DB) error {
-	err := dropTables(db)
+	db, err := sql.Open("postgres", url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = createTables(db)
+	// sql.Open does not connect to the database yet, so make sure it is reachable
+	err = db.Ping()
 	if err != nil {
-		return err
+		db.Close()
+		return nil, err
 	}
 
-	return setupProjects(db)
+	return db, nil
 }
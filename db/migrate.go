@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// migrationLock is the key of the PostgreSQL advisory lock that is held while
+// migrating the schema, so multiple instances can be started at the same time.
+const migrationLock = 0x53504f4e4745 // "SPONGE"
+
+type Migration struct {
+	Version     int
+	Description string
+
+	// Applied is the time the migration was applied, or the zero time if it is pending.
+	Applied time.Time
+
+	sql string
+}
+
+func (m *Migration) Pending() bool {
+	return m.Applied.IsZero()
+}
+
+// LatestSchemaVersion returns the schema version after applying all migrations.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the last migration applied to the database.
+func SchemaVersion(db *sql.DB) (version int, err error) {
+	err = db.QueryRow("SELECT coalesce(MAX(version), 0) FROM schema_migrations;").Scan(&version)
+	return
+}
+
+// Migrate applies all pending migrations and returns them.
+func Migrate(db *sql.DB) ([]*Migration, error) {
+	ctx := context.Background()
+
+	// The advisory lock is held by the connection, so we need to use the same one for all queries
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1);", migrationLock)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1);", migrationLock)
+
+	err = createMigrationsTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := readMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*Migration
+	for _, m := range status {
+		if !m.Pending() {
+			continue
+		}
+
+		err = m.apply(ctx, conn)
+		if err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// MigrationStatus returns all known migrations, including the time they were applied.
+func MigrationStatus(db *sql.DB) ([]*Migration, error) {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	err = createMigrationsTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return readMigrations(ctx, conn)
+}
+
+func createMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT current_timestamp
+		);
+	`)
+	return err
+}
+
+func readMigrations(ctx context.Context, conn *sql.Conn) ([]*Migration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var t time.Time
+		err = rows.Scan(&version, &t)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[version] = t
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*Migration, len(migrations))
+	for i, m := range migrations {
+		status := *m
+		status.Applied = applied[m.Version]
+		result[i] = &status
+	}
+
+	return result, nil
+}
+
+func (m *Migration) apply(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(m.sql)
+	if err != nil {
+		return &MigrationError{m.Version, err}
+	}
+
+	err = tx.QueryRow("INSERT INTO schema_migrations (version, description) VALUES ($1, $2) RETURNING applied;",
+		m.Version, m.Description).Scan(&m.Applied)
+	if err != nil {
+		return &MigrationError{m.Version, err}
+	}
+
+	return tx.Commit()
+}
+
+type MigrationError struct {
+	Version int
+	Cause   error
+}
+
+func (e *MigrationError) Error() string {
+	return "Failed to apply migration " + strconv.Itoa(e.Version) + ": " + e.Cause.Error()
+}
@@ -0,0 +1,204 @@
+package db
+
+// migrations contains all schema migrations, ordered by their version.
+// Migrations must never be changed once released, add a new one instead.
+var migrations = []*Migration{
+	{
+		Version:     1,
+		Description: "Create initial schema",
+
+		// Use IF NOT EXISTS so databases created before the migrations were introduced are adopted as-is
+		sql: `
+			CREATE TABLE IF NOT EXISTS projects (
+				project_id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+
+				group_id TEXT NOT NULL,
+				artifact_id TEXT NOT NULL,
+				UNIQUE(group_id, artifact_id),
+
+				plugin_id TEXT,
+
+				github_owner TEXT NOT NULL,
+				github_repo TEXT NOT NULL,
+				UNIQUE(github_owner, github_repo),
+
+				use_snapshots BOOLEAN NOT NULL,
+				use_semver BOOLEAN NOT NULL,
+
+				last_updated TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT current_timestamp
+			);
+
+			CREATE TABLE IF NOT EXISTS build_types (
+				build_type_id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				allows_promotion BOOLEAN NOT NULL
+			);
+
+			CREATE TABLE IF NOT EXISTS project_build_types (
+				project_id INT NOT NULL REFERENCES projects ON DELETE CASCADE ON UPDATE CASCADE,
+				build_type_id INT NOT NULL REFERENCES build_types ON DELETE CASCADE ON UPDATE CASCADE,
+				PRIMARY KEY(project_id, build_type_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS downloads (
+				download_id SERIAL PRIMARY KEY,
+				project_id INT NOT NULL REFERENCES projects ON DELETE CASCADE ON UPDATE CASCADE,
+				build_type_id INT NOT NULL REFERENCES build_types ON DELETE RESTRICT ON UPDATE CASCADE,
+
+				version TEXT NOT NULL,
+				snapshot_version TEXT,
+				published TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+				branch TEXT NOT NULL,
+				commit CHAR(40) NOT NULL,
+
+				label TEXT,
+				changelog JSONB,
+
+				UNIQUE(project_id, version),
+				UNIQUE(build_type_id, published)
+			);
+
+			CREATE TABLE IF NOT EXISTS dependencies (
+				download_id INT NOT NULL REFERENCES downloads ON DELETE CASCADE ON UPDATE CASCADE,
+				name TEXT NOT NULL,
+				version TEXT NOT NULL,
+				PRIMARY KEY(download_id, name)
+			);
+
+			CREATE TABLE IF NOT EXISTS artifacts (
+				download_id INT NOT NULL REFERENCES downloads ON DELETE CASCADE ON UPDATE CASCADE,
+				classifier TEXT,
+				extension TEXT NOT NULL,
+				PRIMARY KEY(download_id, classifier, extension),
+
+				size INT NOT NULL,
+				sha1 CHAR(40) NOT NULL,
+				md5 CHAR(32) NOT NULL
+			);
+		`,
+	},
+	{
+		Version:     2,
+		Description: "Add recommendation policy to projects",
+
+		// Keep the recommendation behavior that was previously hardcoded in the indexer
+		sql: `
+			ALTER TABLE projects ADD COLUMN recommendation TEXT NOT NULL DEFAULT 'never';
+
+			UPDATE projects SET recommendation = 'release' WHERE use_snapshots;
+			UPDATE projects SET recommendation = '!regex:RC' WHERE plugin_id IN ('spongevanilla', 'spongeforge');
+		`,
+	},
+	{
+		Version:     3,
+		Description: "Add yanked flag to downloads",
+		sql: `
+			ALTER TABLE downloads ADD COLUMN yanked BOOLEAN NOT NULL DEFAULT FALSE;
+		`,
+	},
+	{
+		Version:     4,
+		Description: "Add SHA-256 and SHA-512 checksums to artifacts",
+		sql: `
+			ALTER TABLE artifacts ADD COLUMN sha256 CHAR(64), ADD COLUMN sha512 CHAR(128);
+		`,
+	},
+	{
+		Version:     5,
+		Description: "Persist upload sessions",
+		sql: `
+			CREATE TABLE upload_sessions (
+				upload_session_id SERIAL PRIMARY KEY,
+				project_id INT NOT NULL REFERENCES projects ON DELETE CASCADE ON UPDATE CASCADE,
+				version TEXT NOT NULL,
+
+				committed BOOLEAN NOT NULL DEFAULT FALSE,
+
+				created TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT current_timestamp,
+				updated TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT current_timestamp
+			);
+
+			CREATE TABLE upload_session_files (
+				upload_session_id INT NOT NULL REFERENCES upload_sessions ON DELETE CASCADE ON UPDATE CASCADE,
+				path TEXT NOT NULL,
+				PRIMARY KEY(upload_session_id, path)
+			);
+		`,
+	},
+	{
+		Version:     6,
+		Description: "Add variants from Gradle module metadata",
+		sql: `
+			CREATE TABLE variants (
+				download_id INT NOT NULL REFERENCES downloads ON DELETE CASCADE ON UPDATE CASCADE,
+				name TEXT NOT NULL,
+				PRIMARY KEY(download_id, name),
+
+				attributes JSONB NOT NULL,
+				capabilities JSONB,
+				dependencies JSONB,
+				available_at JSONB
+			);
+		`,
+	},
+	{
+		Version:     7,
+		Description: "Add project metadata and dependencies from the POM",
+		sql: `
+			ALTER TABLE downloads ADD COLUMN description TEXT, ADD COLUMN licenses JSONB, ADD COLUMN scm JSONB;
+
+			CREATE TABLE maven_dependencies (
+				download_id INT NOT NULL REFERENCES downloads ON DELETE CASCADE ON UPDATE CASCADE,
+				group_id TEXT NOT NULL,
+				artifact_id TEXT NOT NULL,
+				classifier TEXT NOT NULL,
+				type TEXT NOT NULL,
+				PRIMARY KEY(download_id, group_id, artifact_id, classifier, type),
+
+				version TEXT,
+				scope TEXT NOT NULL,
+				optional BOOLEAN NOT NULL
+			);
+		`,
+	},
+	{
+		Version:     8,
+		Description: "Add plugin metadata to downloads",
+
+		// Existing dependencies are left without the additional information (NULL)
+		sql: `
+			ALTER TABLE downloads ADD COLUMN plugin_name TEXT, ADD COLUMN plugin_description TEXT,
+				ADD COLUMN plugin_url TEXT, ADD COLUMN plugin_authors TEXT[];
+
+			ALTER TABLE dependencies ADD COLUMN required BOOLEAN, ADD COLUMN load_order TEXT;
+		`,
+	},
+	{
+		Version:     9,
+		Description: "Record instance and published files of upload sessions",
+
+		// Sessions without instance were created before and can be recovered by any instance
+		sql: `
+			ALTER TABLE upload_sessions ADD COLUMN instance TEXT;
+			ALTER TABLE upload_session_files ADD COLUMN published BOOLEAN NOT NULL DEFAULT FALSE;
+		`,
+	},
+	{
+		Version:     10,
+		Description: "Add dependency constraints and files to variants",
+
+		sql: `
+			ALTER TABLE variants ADD COLUMN dependency_constraints JSONB, ADD COLUMN files JSONB;
+		`,
+	},
+	{
+		Version:     11,
+		Description: "Allow artifacts larger than 2 GiB",
+
+		sql: `
+			ALTER TABLE artifacts ALTER COLUMN size TYPE BIGINT;
+		`,
+	},
+}
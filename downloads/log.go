@@ -0,0 +1,83 @@
+package downloads
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/macaron.v1"
+	"log"
+	"os"
+	"reflect"
+)
+
+const (
+	// RequestIDHeader contains the ID used to correlate the log messages of a request.
+	// It is taken from the request if set (e.g. by a proxy) and added to the response.
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+var logger = createRootLogger()
+
+// createRootLogger configures the logger shared by all modules. It writes JSON to
+// stdout by default, LOG_FORMAT=text switches to human readable output and
+// LOG_LEVEL changes the minimal level of logged messages. The standard logger
+// is used so packages that cannot depend on this package (e.g. maven) log the
+// same way.
+func createRootLogger() *logrus.Logger {
+	l := logrus.StandardLogger()
+	l.Out = os.Stdout
+
+	if os.Getenv("LOG_FORMAT") == "text" {
+		l.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+	} else {
+		l.Formatter = &logrus.JSONFormatter{}
+	}
+
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		var err error
+		l.Level, err = logrus.ParseLevel(level)
+		if err != nil {
+			l.WithError(err).Fatalln("Invalid LOG_LEVEL")
+		}
+	}
+
+	return l
+}
+
+func CreateLogger(name string) *logrus.Entry {
+	return logger.WithField("module", name)
+}
+
+// CreateStdLogger creates a standard library logger for libraries that do not
+// support structured logging. All lines are logged as info messages.
+func CreateStdLogger(name string) *log.Logger {
+	return log.New(CreateLogger(name).Writer(), "", 0)
+}
+
+// RequestLogger returns the logger of the current request, which is mapped by
+// Module.InitializeContext.
+func RequestLogger(ctx *macaron.Context) *logrus.Entry {
+	v := ctx.GetVal(reflect.TypeOf((*logrus.Entry)(nil)))
+	if v.IsValid() {
+		return v.Interface().(*logrus.Entry)
+	}
+
+	return logrus.NewEntry(logger)
+}
+
+func requestID(ctx *macaron.Context) string {
+	id := ctx.Req.Header.Get(RequestIDHeader)
+	if id != "" && len(id) <= maxRequestIDLength {
+		return id
+	}
+
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b[:])
+}
@@ -3,29 +3,44 @@ package downloads
 import (
 	"database/sql"
 	"github.com/SpongePowered/DownloadIndexer/cache"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/macaron.v1"
-	"log"
-	"os"
 )
 
+// RecommendedLabel is the label of recommended downloads
+const RecommendedLabel = "recommended"
+
 type Manager struct {
 	DB    *sql.DB
 	Cache cache.Cache
 }
 
-func CreateLogger(name string) *log.Logger {
-	return log.New(os.Stdout, "["+name+"] ", log.LstdFlags)
-}
-
 func (m *Manager) Module(name string) *Module {
 	return &Module{m, CreateLogger(name)}
 }
 
 type Module struct {
 	*Manager
-	Log *log.Logger
+	Log *logrus.Entry
 }
 
+// InitializeContext maps the logger for the request, which includes the request ID.
 func (m *Module) InitializeContext(ctx *macaron.Context) {
-	ctx.Map(m.Log)
+	id := requestID(ctx)
+	ctx.Resp.Header().Set(RequestIDHeader, id)
+	ctx.Map(m.Log.WithField("request_id", id))
+}
+
+// ParseIdentifier maps the maven.Identifier from the groupId and artifactId route parameters.
+func ParseIdentifier(ctx *macaron.Context) error {
+	i := maven.Identifier{GroupID: ctx.Params("groupId"), ArtifactID: ctx.Params("artifactId")}
+	if i.GroupID == "" || i.ArtifactID == "" {
+		return httperror.BadRequest("Invalid group or artifact ID", nil)
+	}
+
+	ctx.Map(i)
+	ctx.Next()
+	return nil
 }
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"github.com/SpongePowered/DownloadIndexer/metrics"
 	"gopkg.in/libgit2/git2go.v26"
 	"strings"
 	"time"
@@ -19,6 +20,11 @@ type Commit struct {
 }
 
 func (r *Repository) GenerateChangelog(commitHash, parentHash string) ([]*Commit, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ChangelogDuration.Observe(metrics.Since(start))
+	}()
+
 	// Have we already tried (and failed) to load this commit?
 	if err, ok := r.failedCommits[commitHash]; ok {
 		return nil, err
@@ -111,7 +117,7 @@ func (r *Repository) prepareCommit(commit *git.Commit) *Commit {
 		var err error
 		result.Submodules, err = r.generateSubmoduleChangelog(commit)
 		if err != nil {
-			r.Log.Println("Failed to generate submodule changelog for", commit.Id(), err)
+			r.Log.Warnln("Failed to generate submodule changelog for", commit.Id(), err)
 		}
 	}
 
@@ -150,13 +156,13 @@ func (r *Repository) generateSubmoduleChangelog(commit *git.Commit) (map[string]
 	for path, url := range submodules {
 		subEntry, err := tree.EntryByPath(path)
 		if err != nil {
-			r.Log.Println("Failed to get submodule ref:", err)
+			r.Log.Warnln("Failed to get submodule ref:", err)
 			continue
 		}
 
 		parentSubEntry, err := parentTree.EntryByPath(path)
 		if err != nil {
-			r.Log.Println("Failed to get submodule entry for parent commit:", err)
+			r.Log.Warnln("Failed to get submodule entry for parent commit:", err)
 			continue
 		}
 
@@ -167,13 +173,13 @@ func (r *Repository) generateSubmoduleChangelog(commit *git.Commit) (map[string]
 
 		subRepo, err := r.root.open(url)
 		if err != nil {
-			r.Log.Println("Failed to open submodule repo:", err)
+			r.Log.Warnln("Failed to open submodule repo:", err)
 			continue
 		}
 
 		commits, err := subRepo.generateChangelog(subEntry.Id, parentSubEntry.Id)
 		if err != nil {
-			r.Log.Println("Failed to generate submodule changelog:", err)
+			r.Log.Warnln("Failed to generate submodule changelog:", err)
 			continue
 		}
 
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/metrics"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/libgit2/git2go.v26"
 	"os"
 	"path/filepath"
@@ -25,6 +27,9 @@ type Repository struct {
 	*Manager
 	url string
 
+	// Log is the logger of the current user of the repository (e.g. including the request ID)
+	Log *logrus.Entry
+
 	lock sync.Mutex
 	repo *git.Repository
 
@@ -44,11 +49,18 @@ func Create(manager *downloads.Manager, dir string) (*Manager, error) {
 	return &Manager{Module: manager.Module("Git"), StorageDir: dir, repos: make(map[string]*Repository)}, nil
 }
 
-func (m *Manager) OpenGitHub(owner, repo string) (*Repository, error) {
-	return m.Open("https://github.com/" + owner + "/" + repo + ".git")
+func (m *Manager) OpenGitHub(owner, repo string, log *logrus.Entry) (*Repository, error) {
+	return m.Open("https://github.com/"+owner+"/"+repo+".git", log)
 }
 
-func (m *Manager) Open(url string) (*Repository, error) {
+// Open opens the repository and locks it until it is closed. The log messages
+// of the repository are logged to the specified logger (or the logger of the
+// manager if it is nil).
+func (m *Manager) Open(url string, log *logrus.Entry) (*Repository, error) {
+	if log == nil {
+		log = m.Log
+	}
+
 	m.reposLock.RLock()
 	result := m.repos[url]
 	m.reposLock.RUnlock()
@@ -57,7 +69,7 @@ func (m *Manager) Open(url string) (*Repository, error) {
 		m.reposLock.Lock()
 		defer m.reposLock.Unlock()
 
-		repo, err := m.initRepo(url)
+		repo, err := m.initRepo(url, log)
 		if err != nil {
 			return nil, err
 		}
@@ -67,20 +79,21 @@ func (m *Manager) Open(url string) (*Repository, error) {
 	}
 
 	result.lock.Lock()
+	result.Log = log
 	result.root = result
 	result.children = make(map[string]*Repository)
 	return result, nil
 }
 
-func (m *Manager) initRepo(url string) (*git.Repository, error) {
+func (m *Manager) initRepo(url string, log *logrus.Entry) (*git.Repository, error) {
 	// Repo dir is the MD5 hash of the repo URL
 	hash := md5.Sum([]byte(url))
 	dir := filepath.Join(m.StorageDir, hex.EncodeToString(hash[:]))
 
 	if _, err := os.Stat(dir); !os.IsNotExist(err) {
-		m.Log.Println("Opening", url, "from", dir)
+		log.Println("Opening", url, "from", dir)
 		if repo, err := git.OpenRepository(dir); err != nil {
-			m.Log.Println("Failed to open repo from:", dir, err)
+			log.Warnln("Failed to open repo from:", dir, err)
 			if err = os.RemoveAll(dir); err != nil {
 				return nil, err
 			}
@@ -89,15 +102,17 @@ func (m *Manager) initRepo(url string) (*git.Repository, error) {
 		}
 	}
 
-	m.Log.Println("Cloning", url, "to", dir)
-	return git.Clone(url, dir, &git.CloneOptions{Bare: true})
+	log.Println("Cloning", url, "to", dir)
+	repo, err := git.Clone(url, dir, &git.CloneOptions{Bare: true})
+	metrics.GitOperations.WithLabelValues("clone", metrics.Result(err)).Inc()
+	return repo, err
 }
 
 func (r *Repository) open(url string) (*Repository, error) {
 	c, ok := r.children[url]
 	if !ok {
 		var err error
-		c, err = r.Open(url)
+		c, err = r.Open(url, r.Log)
 		if c != nil {
 			c.root = r
 		}
@@ -133,7 +148,9 @@ func (r *Repository) fetch() (err error) {
 		return
 	}
 
-	return remote.Fetch([]string{}, &git.FetchOptions{Prune: git.FetchPruneOn}, "")
+	err = remote.Fetch([]string{}, &git.FetchOptions{Prune: git.FetchPruneOn}, "")
+	metrics.GitOperations.WithLabelValues("fetch", metrics.Result(err)).Inc()
+	return
 }
 
 func (r *Repository) Close() {
@@ -23,7 +23,7 @@ func (r *Repository) readSubmodules(tree *git.Tree) (map[string]string, error) {
 	return r.readGitModules(blob.Contents()), nil
 }
 
-func (m *Manager) readGitModules(data []byte) map[string]string {
+func (r *Repository) readGitModules(data []byte) map[string]string {
 	result := make(map[string]string)
 
 	skip := false
@@ -42,7 +42,7 @@ func (m *Manager) readGitModules(data []byte) map[string]string {
 			if strings.HasPrefix(line, "[submodule") {
 				skip = false
 				if path != "" || url != "" {
-					m.Log.Println("Incomplete submodule configuration", path, url)
+					r.Log.Warnln("Incomplete submodule configuration", path, url)
 					path = ""
 					url = ""
 				}
@@ -59,7 +59,7 @@ func (m *Manager) readGitModules(data []byte) map[string]string {
 
 		pos := strings.IndexByte(line, '=')
 		if pos == -1 {
-			m.Log.Println("Invalid key-value pair in submodule configuration", line)
+			r.Log.Warnln("Invalid key-value pair in submodule configuration", line)
 			continue
 		}
 
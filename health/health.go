@@ -0,0 +1,143 @@
+// Package health reports the health of the dependencies of the enabled modules.
+package health
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"gopkg.in/macaron.v1"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkTimeout is the maximal time to wait for a single check.
+const checkTimeout = 10 * time.Second
+
+type Check func() error
+
+type check struct {
+	module string
+	name   string
+	check  Check
+}
+
+type Health struct {
+	checks []check
+}
+
+type status struct {
+	Status  string                       `json:"status"`
+	Modules map[string]map[string]string `json:"modules,omitempty"`
+}
+
+// Register adds a check for the specified module.
+func (h *Health) Register(module, name string, c Check) {
+	h.checks = append(h.checks, check{module, name, c})
+}
+
+// Setup registers /healthz and /readyz. /healthz only reports that the
+// application is running, /readyz runs all checks and fails if any of them
+// fails.
+func (h *Health) Setup(m *macaron.Macaron, renderer macaron.Handler) {
+	m.Get("/healthz", renderer, func(ctx *macaron.Context) {
+		ctx.JSON(http.StatusOK, &status{Status: "ok"})
+	})
+	m.Get("/readyz", renderer, h.Ready)
+}
+
+func (h *Health) Ready(ctx *macaron.Context) {
+	result := &status{Status: "ok", Modules: make(map[string]map[string]string)}
+
+	errs := h.run()
+	for i, c := range h.checks {
+		module, ok := result.Modules[c.module]
+		if !ok {
+			module = make(map[string]string)
+			result.Modules[c.module] = module
+		}
+
+		if errs[i] != nil {
+			result.Status = "fail"
+			module[c.name] = errs[i].Error()
+		} else {
+			module[c.name] = "ok"
+		}
+	}
+
+	code := http.StatusOK
+	if result.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+
+	ctx.JSON(code, result)
+}
+
+// run runs all checks in parallel and returns their errors.
+func (h *Health) run() []error {
+	errs := make([]error, len(h.checks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(h.checks))
+
+	for i, c := range h.checks {
+		go func(i int, c Check) {
+			defer wg.Done()
+			errs[i] = runWithTimeout(c)
+		}(i, c.check)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func runWithTimeout(c Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(checkTimeout):
+		return fmt.Errorf("timed out after %s", checkTimeout)
+	}
+}
+
+// Database checks the connection to the database and that all migrations were applied.
+func Database(postgresDB *sql.DB) Check {
+	return func() error {
+		err := postgresDB.Ping()
+		if err != nil {
+			return err
+		}
+
+		version, err := db.SchemaVersion(postgresDB)
+		if err != nil {
+			return err
+		}
+
+		// Newer versions are fine, they are applied by newer instances during rolling deploys
+		if latest := db.LatestSchemaVersion(); version < latest {
+			return fmt.Errorf("schema version is %d, expected %d", version, latest)
+		}
+
+		return nil
+	}
+}
+
+// WritableDir checks that files can be created in the directory.
+func WritableDir(dir string) Check {
+	return func() error {
+		f, err := ioutil.TempFile(dir, ".healthcheck")
+		if err != nil {
+			return err
+		}
+
+		f.Close()
+		return os.Remove(f.Name())
+	}
+}
@@ -1,8 +1,8 @@
 package httperror
 
 import (
+	"github.com/sirupsen/logrus"
 	"gopkg.in/macaron.v1"
-	"log"
 	"net/http"
 	"reflect"
 )
@@ -25,15 +25,19 @@ func Handler() macaron.ReturnHandler {
 			httpError, ok := err.(*HTTPError)
 
 			if !ok || httpError.Message != "" || httpError.Cause != nil {
-				logger := ctx.GetVal(reflect.TypeOf((*log.Logger)(nil))).Interface().(*log.Logger)
-				logger.Println(err)
+				logError(ctx, err, httpError)
 			}
 
 			if !ctx.Written() {
-				if ok {
-					http.Error(ctx.Resp, httpError.Message, httpError.Code)
+				if !ok {
+					// Never expose the internal error to clients
+					httpError = &HTTPError{Code: http.StatusInternalServerError, Message: "Internal Server Error"}
+				}
+
+				if httpError.Code != http.StatusNotModified && acceptsProblem(ctx) {
+					writeProblem(ctx, httpError)
 				} else {
-					http.Error(ctx.Resp, "Internal Server Error", http.StatusInternalServerError)
+					http.Error(ctx.Resp, httpError.Message, httpError.Code)
 				}
 			}
 		default:
@@ -41,3 +45,24 @@ func Handler() macaron.ReturnHandler {
 		}
 	}
 }
+
+func logError(ctx *macaron.Context, err error, httpError *HTTPError) {
+	var logger *logrus.Entry
+	if v := ctx.GetVal(reflect.TypeOf((*logrus.Entry)(nil))); v.IsValid() {
+		logger = v.Interface().(*logrus.Entry)
+	} else {
+		logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	code := http.StatusInternalServerError
+	if httpError != nil {
+		code = httpError.Code
+	}
+
+	logger = logger.WithFields(logrus.Fields{"status": code, "path": ctx.Req.URL.Path})
+	if code >= http.StatusInternalServerError {
+		logger.WithError(err).Errorln("Request failed")
+	} else {
+		logger.WithError(err).Warnln("Request rejected")
+	}
+}
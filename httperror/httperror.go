@@ -8,9 +8,16 @@ type HTTPError struct {
 	Code    int
 	Message string
 
+	// Type is a machine-readable code for the error (e.g. checksum-mismatch)
+	Type string
+	// Details are additional values reported to clients that understand problem details
+	Details Details
+
 	Cause error
 }
 
+type Details map[string]interface{}
+
 func (e *HTTPError) Error() string {
 	result := http.StatusText(e.Code)
 	if e.Message != "" {
@@ -23,7 +30,12 @@ func (e *HTTPError) Error() string {
 }
 
 func New(code int, message string, cause error) error {
-	return &HTTPError{code, message, cause}
+	return &HTTPError{Code: code, Message: message, Cause: cause}
+}
+
+// Problem creates an error with a machine-readable type and optional details.
+func Problem(code int, errorType string, message string, details Details) error {
+	return &HTTPError{Code: code, Message: message, Type: errorType, Details: details}
 }
 
 func BadRequest(message string, cause error) error {
@@ -38,6 +50,12 @@ func NotFound(message string) error {
 	return New(http.StatusNotFound, message, nil)
 }
 
+// IsNotFound returns true if the error is a 404 Not Found error.
+func IsNotFound(err error) bool {
+	httpErr, ok := err.(*HTTPError)
+	return ok && httpErr.Code == http.StatusNotFound
+}
+
 func InternalError(message string, cause error) error {
 	return New(http.StatusInternalServerError, message, cause)
 }
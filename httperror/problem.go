@@ -0,0 +1,68 @@
+package httperror
+
+import (
+	"encoding/json"
+	"gopkg.in/macaron.v1"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	preferProblemKey = "httperror.PreferProblem"
+)
+
+// PreferProblem returns errors as problem details even if the client did not
+// explicitly ask for them. It should be used for routes that respond with JSON.
+func PreferProblem(ctx *macaron.Context) {
+	ctx.Data[preferProblemKey] = true
+}
+
+// acceptsProblem checks if the error should be returned as problem details.
+// Maven clients don't send a JSON Accept header, so they get plain text unless
+// the route prefers problem details.
+func acceptsProblem(ctx *macaron.Context) bool {
+	accept := ctx.Req.Header.Get("Accept")
+	if strings.Contains(accept, ProblemContentType) || strings.Contains(accept, "application/json") {
+		return true
+	}
+
+	prefer, _ := ctx.Data[preferProblemKey].(bool)
+	return prefer
+}
+
+// problem returns the JSON representation of the error as specified in RFC 7807.
+// The details are added as extension members next to the standard members.
+func problem(e *HTTPError) map[string]interface{} {
+	p := make(map[string]interface{}, len(e.Details)+4)
+	for key, value := range e.Details {
+		p[key] = value
+	}
+
+	p["type"] = e.Type
+	if e.Type == "" {
+		p["type"] = "about:blank"
+	}
+
+	p["title"] = http.StatusText(e.Code)
+	p["status"] = e.Code
+
+	if e.Message != "" {
+		p["detail"] = e.Message
+	} else {
+		delete(p, "detail")
+	}
+
+	return p
+}
+
+func writeProblem(ctx *macaron.Context, e *HTTPError) {
+	p := problem(e)
+
+	header := ctx.Resp.Header()
+	header.Set("Content-Type", ProblemContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	ctx.Resp.WriteHeader(e.Code)
+	json.NewEncoder(ctx.Resp).Encode(p)
+}
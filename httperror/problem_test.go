@@ -0,0 +1,54 @@
+package httperror
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			"without type",
+			NotFound("File does not exist"),
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "File does not exist"}`,
+		},
+		{
+			"with details",
+			Problem(http.StatusBadRequest, "version-mismatch", "Version mismatch", Details{"expected": "1.0", "actual": "2.0"}),
+			`{"type": "version-mismatch", "title": "Bad Request", "status": 400, "detail": "Version mismatch",
+				"expected": "1.0", "actual": "2.0"}`,
+		},
+		{
+			"details do not override standard members",
+			Problem(http.StatusConflict, "conflict", "", Details{"status": 200, "detail": "overridden"}),
+			`{"type": "conflict", "title": "Conflict", "status": 409}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(problem(test.err.(*HTTPError)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual, expected map[string]interface{}
+			if err = json.Unmarshal(data, &actual); err != nil {
+				t.Fatal(err)
+			}
+			if err = json.Unmarshal([]byte(test.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("got %s, expected %s", data, test.expected)
+			}
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"flag"
 	"github.com/SpongePowered/DownloadIndexer/downloads"
 	"github.com/SpongePowered/DownloadIndexer/git"
+	"github.com/SpongePowered/DownloadIndexer/health"
 	"github.com/SpongePowered/DownloadIndexer/indexer"
 	"github.com/SpongePowered/DownloadIndexer/maven"
 	"gopkg.in/macaron.v1"
+	"os"
+	"strconv"
+	"strings"
 )
 
-func setupIndexer(manager *downloads.Manager, m *macaron.Macaron) {
+func setupIndexer(manager *downloads.Manager, m *macaron.Macaron, h *health.Health) *indexer.Indexer {
 	authHandler := setupAuthentication("UPLOAD_AUTH")
 	uploadURL := requireEnv("UPLOAD_URL")
 	gitStorage := requireEnv("GIT_STORAGE_DIR")
@@ -32,5 +37,84 @@ func setupIndexer(manager *downloads.Manager, m *macaron.Macaron) {
 		logger.Fatalln(err)
 	}
 
+	if maxSize := os.Getenv("UPLOAD_MAX_SIZE"); maxSize != "" {
+		i.MaxFileSize, err = strconv.ParseInt(maxSize, 10, 64)
+		if err != nil {
+			logger.Fatalln("Invalid UPLOAD_MAX_SIZE:", err)
+		}
+	}
+
+	i.TempDir = os.Getenv("UPLOAD_TEMP_DIR")
+	i.StagingDir = os.Getenv("UPLOAD_STAGING_DIR")
+	if instance := os.Getenv("INSTANCE_ID"); instance != "" {
+		i.Instance = instance
+	}
+
+	h.Register("indexer", "git_storage", health.WritableDir(gitStorage))
+	h.Register("indexer", "temp_dir", health.WritableDir(i.TempDir))
+	h.Register("indexer", "staging_dir", i.CheckStagingDir)
+	h.Register("indexer", "repository", repo.Check)
+
+	i.StartSessionRecovery()
 	i.Setup(m, authHandler)
+	return i
+}
+
+func importCommand(args []string) bool {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	repoURL := flags.String("repo", os.Getenv("UPLOAD_URL"), "URL to the Maven repository to import from")
+	classifiers := flags.String("classifiers", "sources,dev", "Comma separated list of side artifacts to import")
+
+	opts := new(indexer.ImportOptions)
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "Index all versions without saving them")
+	flags.BoolVar(&opts.Resume, "resume", false, "Skip versions that are already indexed")
+	flags.StringVar(&opts.BuildType, "type", "", "Override build type of all versions")
+	flags.StringVar(&opts.Branch, "branch", "", "Override branch of all versions")
+	flags.BoolVar(&opts.RequireChangelog, "require-changelog", false, "Fail if the changelog cannot be generated")
+
+	flags.Parse(args)
+	if flags.NArg() != 1 || *repoURL == "" {
+		return false
+	}
+
+	pos := strings.IndexByte(flags.Arg(0), ':')
+	if pos == -1 {
+		return false
+	}
+
+	identifier := maven.Identifier{GroupID: flags.Arg(0)[:pos], ArtifactID: flags.Arg(0)[pos+1:]}
+
+	if *classifiers != "" {
+		opts.Classifiers = strings.Split(*classifiers, ",")
+	}
+
+	source, err := maven.CreateRepository(*repoURL)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	manager := &downloads.Manager{
+		DB:    setupDatabase(),
+		Cache: setupCache(),
+	}
+
+	gitManager, err := git.Create(manager, requireEnv("GIT_STORAGE_DIR"))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	i := indexer.Create(manager, source, gitManager)
+	i.TempDir = os.Getenv("UPLOAD_TEMP_DIR")
+
+	err = i.LoadProjects()
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	err = i.Import(source, identifier, opts)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	return true
 }
@@ -1,16 +1,21 @@
 package indexer
 
 import (
-	"bytes"
 	"database/sql"
 	"github.com/SpongePowered/DownloadIndexer/downloads"
 	"github.com/SpongePowered/DownloadIndexer/git"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/gradle"
+	"github.com/SpongePowered/DownloadIndexer/indexer/pom"
+	"github.com/SpongePowered/DownloadIndexer/indexer/recommend"
 	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/SpongePowered/DownloadIndexer/metrics"
 	"github.com/Unknwon/com"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/macaron.v1"
 	"io"
 	"net/http"
+	"os"
 	"reflect"
 	"regexp"
 	"strings"
@@ -21,7 +26,7 @@ import (
 type metaState int
 
 const (
-	maxFileSize = 64 * 1024 * 1024 // 64 MB
+	DefaultMaxFileSize = 64 * 1024 * 1024 // 64 MB
 
 	jarExtension = "jar"
 
@@ -32,7 +37,6 @@ const (
 
 const (
 	metaPending metaState = iota
-	metaLocked
 	metaDone
 )
 
@@ -45,7 +49,20 @@ type Indexer struct {
 	repo maven.Repository
 	git  *git.Manager
 
-	projects    map[maven.Identifier]*project
+	// MaxFileSize is the maximal size of uploaded files (in bytes)
+	MaxFileSize int64
+	// TempDir is the directory uploaded files are stored in while they are processed (defaults to os.TempDir)
+	TempDir string
+	// StagingDir is the directory files are staged in until their session is committed
+	StagingDir string
+	// Instance identifies the instance in the persisted sessions, only the
+	// instance that created a session can recover it (defaults to the hostname,
+	// which may change on restart, e.g. for containers)
+	Instance string
+
+	projects     map[maven.Identifier]*project
+	projectsLock sync.RWMutex
+
 	sessions    map[string]*session
 	sessionLock sync.RWMutex
 }
@@ -61,11 +78,18 @@ type project struct {
 	useSnapshots bool
 	useSemVer    bool
 
-	lock sync.Mutex
+	recommendation recommend.Policy
+
+	// Shared with the previous instance of the project when reloading
+	lock *sync.Mutex
 }
 
 type session struct {
-	id string
+	id   string
+	dbID int
+
+	// Logger of the current request
+	log *logrus.Entry
 
 	project *project
 	version string
@@ -75,8 +99,12 @@ type session struct {
 	downloadID int
 	artifacts  map[artifactType]*artifact
 
-	failed  bool
-	timeout *time.Timer
+	// Paths of the files that are published once the session is committed
+	staged []string
+
+	failed    bool
+	committed bool
+	timeout   *time.Timer
 
 	lockedProject bool
 	projectMeta   metaState
@@ -89,15 +117,21 @@ type artifact struct {
 	uploaded bool
 	md5      string
 	sha1     string
+	sha256   string
+	sha512   string
 }
 
 func Create(m *downloads.Manager, repo maven.Repository, git *git.Manager) *Indexer {
+	instance, _ := os.Hostname()
+
 	return &Indexer{
-		Module:   m.Module("Indexer"),
-		repo:     repo,
-		git:      git,
-		projects: make(map[maven.Identifier]*project),
-		sessions: make(map[string]*session),
+		Module:      m.Module("Indexer"),
+		Instance:    instance,
+		repo:        repo,
+		git:         git,
+		MaxFileSize: DefaultMaxFileSize,
+		projects:    make(map[maven.Identifier]*project),
+		sessions:    make(map[string]*session),
 	}
 }
 
@@ -105,27 +139,66 @@ func (i *Indexer) LoadProjects() error {
 	i.Log.Println("Loading projects")
 
 	rows, err := i.DB.Query("SELECT project_id, group_id, artifact_id, plugin_id, github_owner, github_repo, " +
-		"use_snapshots, use_semver FROM projects;")
+		"use_snapshots, use_semver, recommendation FROM projects;")
 	if err != nil {
 		return err
 	}
 
+	defer rows.Close()
+
+	projects := make(map[maven.Identifier]*project)
+
 	for rows.Next() {
 		var identifier maven.Identifier
+		var pluginID sql.NullString
+		var recommendation string
 		project := new(project)
 
-		err = rows.Scan(&project.id, &identifier.GroupID, &identifier.ArtifactID, &project.pluginID,
-			&project.githubOwner, &project.githubRepo, &project.useSnapshots, &project.useSemVer)
+		err = rows.Scan(&project.id, &identifier.GroupID, &identifier.ArtifactID, &pluginID,
+			&project.githubOwner, &project.githubRepo, &project.useSnapshots, &project.useSemVer, &recommendation)
+		if err != nil {
+			return err
+		}
+
+		project.recommendation, err = recommend.Parse(recommendation)
 		if err != nil {
 			return err
 		}
 
-		i.projects[identifier] = project
+		project.pluginID = pluginID.String
+		projects[identifier] = project
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	i.projectsLock.Lock()
+	defer i.projectsLock.Unlock()
+
+	// Keep the locks of already loaded projects, they might be held by active sessions
+	locks := make(map[int]*sync.Mutex)
+	for _, p := range i.projects {
+		locks[p.id] = p.lock
 	}
 
+	for _, p := range projects {
+		p.lock = locks[p.id]
+		if p.lock == nil {
+			p.lock = new(sync.Mutex)
+		}
+	}
+
+	i.projects = projects
 	return nil
 }
 
+func (i *Indexer) getProject(identifier maven.Identifier) *project {
+	i.projectsLock.RLock()
+	defer i.projectsLock.RUnlock()
+	return i.projects[identifier]
+}
+
 func (i *Indexer) Setup(m *macaron.Macaron, auth macaron.Handler) {
 	m.Group("/maven/upload", func() {
 		m.Get("/*", i.Get)
@@ -150,7 +223,7 @@ func (i *Indexer) Get(ctx *macaron.Context) error {
 		return httperror.Forbidden("Can only download maven metadata")
 	}
 
-	project := i.projects[p.Identifier]
+	project := i.getProject(p.Identifier)
 	if project == nil {
 		return i.repo.Download(path, ctx.Resp)
 	}
@@ -163,7 +236,7 @@ func (i *Indexer) Get(ctx *macaron.Context) error {
 	} else if project.useSnapshots {
 		s, err = i.getOrCreateSession(ctx, project, p.version)
 	} else {
-		return httperror.Forbidden("Project does not allow snapshots")
+		return httperror.Problem(http.StatusForbidden, "snapshots-not-allowed", "Project does not allow snapshots", nil)
 	}
 
 	if err != nil {
@@ -176,32 +249,10 @@ func (i *Indexer) Get(ctx *macaron.Context) error {
 		return httperror.New(http.StatusFailedDependency, "Previous request failed", nil)
 	}
 
-	if p.t == file {
-		var meta *metaState
-
-		if p.version == "" {
-			meta = &s.projectMeta
-		} else {
-			meta = &s.versionMeta
-		}
-
-		if *meta == metaPending {
-			*meta = metaLocked
-		}
-	}
-
-	return i.repo.Download(path, ctx.Resp)
+	return i.serveMetadata(s, p, ctx.Resp)
 }
 
 func (i *Indexer) Put(ctx *macaron.Context) error {
-	if ctx.Req.ContentLength <= 0 {
-		return httperror.New(http.StatusLengthRequired, "Missing content length", nil)
-	}
-
-	if ctx.Req.ContentLength > maxFileSize {
-		return httperror.BadRequest("File exceeds maximal file size", nil)
-	}
-
 	path := ctx.Params("*")
 
 	p, err := parsePath(path, true)
@@ -209,13 +260,18 @@ func (i *Indexer) Put(ctx *macaron.Context) error {
 		return httperror.BadRequest("Invalid path", err)
 	}
 
-	project := i.projects[p.Identifier]
+	project := i.getProject(p.Identifier)
 	if project == nil {
+		err = i.checkContentLength(ctx.Req.Request)
+		if err != nil {
+			return err
+		}
+
 		return i.repo.Upload(path, ctx.Req.Body().ReadCloser(), ctx.Req.ContentLength)
 	}
 
 	if p.snapshot && !project.useSnapshots {
-		return httperror.BadRequest("Project does not use snapshots", nil)
+		return httperror.Problem(http.StatusBadRequest, "snapshots-not-allowed", "Project does not use snapshots", nil)
 	}
 
 	main := p.artifact.classifier == "" && p.artifact.extension == jarExtension
@@ -242,14 +298,66 @@ func (i *Indexer) Put(ctx *macaron.Context) error {
 		return httperror.New(http.StatusFailedDependency, "Previous request failed", nil)
 	}
 
-	// Read file from request body (with the specified length)
-	data := make([]byte, ctx.Req.ContentLength)
-	_, err = io.ReadFull(ctx.Req.Body().ReadCloser(), data)
+	err = i.checkContentLength(ctx.Req.Request)
+	if err != nil {
+		s.fail(i)
+		return err
+	}
+
+	body := ctx.Req.Body().ReadCloser()
+	length := ctx.Req.ContentLength
+
+	// Override query params (e.g. to index older builds)
+	var buildType, branch string
+	var metadataBytes []byte
+	var published time.Time
+	requireChangelog := true
+
+	if main && p.t == file && macaron.Env == macaron.DEV {
+		buildType, branch = ctx.Query("type"), ctx.Query("branch")
+
+		// The metadata is prepended to the JAR file
+		if metadataSize := ctx.QueryInt("mcmodMetadataSize"); metadataSize > 0 && int64(metadataSize) < length {
+			metadataBytes = make([]byte, metadataSize)
+			_, err = io.ReadFull(body, metadataBytes)
+			if err != nil {
+				return httperror.BadRequest("Failed to read input", err)
+			}
+
+			length -= int64(metadataSize)
+		}
+
+		if publishedString := ctx.Query("published"); publishedString != "" {
+			published, err = time.Parse(time.RFC3339, publishedString)
+			if err != nil {
+				return httperror.BadRequest("Failed to parse published date", err)
+			}
+		}
+
+		if _, ok := ctx.Req.Form["requireChangelog"]; ok && !ctx.QueryBool("requireChangelog") {
+			requireChangelog = false
+		}
+	}
+
+	// Stream file from request body (with the specified length) to a temporary file
+	start := time.Now()
+	u, err := i.receive(func(w io.Writer) error {
+		return copyBody(w, body, length)
+	})
 	if err != nil {
+		// Never index a file that does not match what the client declared
+		s.fail(i)
+
+		if _, ok := err.(*httperror.HTTPError); ok {
+			return err
+		}
 		return httperror.BadRequest("Failed to read input", err)
 	}
 
-	// TODO: Error when file is longer than specified?
+	defer u.remove()
+
+	metrics.UploadDuration.Observe(metrics.Since(start))
+	metrics.UploadBytes.Add(float64(u.size))
 
 	if !p.metadata {
 		a := s.artifacts[p.artifact]
@@ -263,110 +371,133 @@ func (i *Indexer) Put(ctx *macaron.Context) error {
 		switch p.t {
 		case file:
 			if a.uploaded {
-				return httperror.New(http.StatusConflict, "Artifact already uploaded", nil)
+				return httperror.Problem(http.StatusConflict, "artifact-exists", "Artifact already uploaded", nil)
 			}
 
 			if main {
-				// Override query params (e.g. to index older builds)
-				var buildType, branch string
-				var metadataBytes []byte
-				var published time.Time
-				requireChangelog := true
-
-				if macaron.Env == macaron.DEV {
-					buildType, branch = ctx.Query("type"), ctx.Query("branch")
-
-					if metadataSize := ctx.QueryInt("mcmodMetadataSize"); metadataSize > 0 {
-						metadataBytes = data[:metadataSize]
-						data = data[metadataSize:]
-					}
-
-					if publishedString := ctx.Query("published"); publishedString != "" {
-						published, err = time.Parse(time.RFC3339, publishedString)
-						if err != nil {
-							return httperror.BadRequest("Failed to parse published date", err)
-						}
-					}
-
-					if _, ok := ctx.Req.Form["requireChangelog"]; ok && !ctx.QueryBool("requireChangelog") {
-						requireChangelog = false
-					}
-				}
-
-				recommended := project.useSnapshots && !p.snapshot
-
-				// TODO: Remove this or move to some kind of project specific code
-				if project.pluginID == "spongevanilla" || project.pluginID == "spongeforge" {
-					recommended = !strings.Contains(p.version, "RC")
-				}
-
-				err = s.createDownload(i, p.displayVersion, data, buildType, branch, metadataBytes, published,
-					recommended, requireChangelog)
+				err = s.createDownload(i, p.displayVersion, u, buildType, branch, metadataBytes, published,
+					p.snapshot, requireChangelog)
 				if err != nil {
 					return err
 				}
 			} else if s.downloadID == 0 {
-				return httperror.BadRequest("Must upload main artifact first", nil)
+				return httperror.Problem(http.StatusBadRequest, "missing-main-artifact", "Must upload main artifact first", nil)
 			}
 
-			// TODO: Currently only JARs are indexed, all other artifacts only verified
-			err = a.create(s, p.artifact, data, p.artifact.extension == jarExtension)
+			// JARs and Gradle module metadata are indexed, all other artifacts are only verified
+			module := p.artifact.classifier == "" && p.artifact.extension == gradle.ModuleExtension
+			err = a.create(s, p.artifact, u, p.artifact.extension == jarExtension || module)
 			if err != nil {
 				return err
 			}
-		case md5File:
-			err = a.setOrVerifyMD5(decodeHash(data))
+
+			if module {
+				err = s.indexModule(p, u)
+			} else if p.artifact.classifier == "" && p.artifact.extension == pom.Extension {
+				err = s.indexPOM(p, u)
+			}
+
 			if err != nil {
 				return err
 			}
-		case sha1File:
-			err = a.setOrVerifySHA1(decodeHash(data))
+		default:
+			data, err := u.bytes()
+			if err != nil {
+				return httperror.InternalError("Failed to read checksum", err)
+			}
+
+			checksum := decodeHash(data)
+
+			switch p.t {
+			case md5File:
+				err = a.setOrVerifyMD5(checksum)
+			case sha1File:
+				err = a.setOrVerifySHA1(checksum)
+			case sha256File:
+				err = a.setOrVerifySHA256(checksum)
+			case sha512File:
+				err = a.setOrVerifySHA512(checksum)
+			}
+
 			if err != nil {
 				return err
 			}
 		}
-	} else if p.t == file {
-		var meta *metaState
-
-		if p.version == "" {
-			meta = &s.projectMeta
-		} else {
-			meta = &s.versionMeta
+	} else {
+		// The metadata is generated from the index, the uploaded metadata is only validated
+		if p.t != file {
+			return nil
 		}
 
-		if *meta != metaLocked {
-			return httperror.Forbidden("Cannot modify metadata without a lock")
+		err = verifyMetadata(u, p)
+		if err != nil {
+			return err
 		}
 
-		*meta = metaDone
+		if p.version == "" {
+			s.projectMeta = metaDone
+		} else {
+			s.versionMeta = metaDone
+		}
 
 		if s.projectMeta == metaDone && s.versionMeta == metaDone && s.tx != nil {
 			// Woo, we're done!
-			err = s.tx.Commit()
+			err = i.stageMetadata(s, p.Identifier, "")
 			if err != nil {
 				return err
 			}
 
-			s.tx = nil
-			s.release(i)
-
-			go func() {
-				// Attempt to update project timestamp
-				_, err := i.DB.Exec("UPDATE projects SET last_updated = current_timestamp WHERE project_id = $1;", s.project.id)
+			if strings.HasSuffix(s.version, snapshotSuffix) {
+				err = i.stageMetadata(s, p.Identifier, s.version)
 				if err != nil {
-					i.Log.Println("Failed to update project timestamp:", err)
+					return err
 				}
-			}()
+			}
 
-			if i.Cache != nil {
-				go i.Cache.PurgeProject(p.Identifier)
+			err = s.commit(i)
+			if err != nil {
+				return err
 			}
 
 			// We let the timeout do its work to cleanup the session
+			return nil
 		}
+
+		return nil
+	}
+
+	if !s.committed {
+		// Hold files back until the session is committed
+		return i.stage(s, path, u)
 	}
 
-	return i.repo.Upload(path, bytes.NewReader(data), int64(len(data)))
+	f, err := u.open()
+	if err != nil {
+		return httperror.InternalError("Failed to open uploaded file", err)
+	}
+
+	defer f.Close()
+	return i.repo.Upload(path, f, u.size)
+}
+
+func (i *Indexer) checkContentLength(req *http.Request) error {
+	for _, encoding := range req.TransferEncoding {
+		if encoding == "chunked" {
+			return httperror.Problem(http.StatusLengthRequired, "length-required",
+				"Chunked transfer encoding is not supported", nil)
+		}
+	}
+
+	if req.ContentLength <= 0 {
+		return httperror.Problem(http.StatusLengthRequired, "length-required", "Missing content length", nil)
+	}
+
+	if req.ContentLength > i.MaxFileSize {
+		return httperror.Problem(http.StatusRequestEntityTooLarge, "file-too-large", "File exceeds maximal file size",
+			httperror.Details{"maxSize": i.MaxFileSize, "size": req.ContentLength})
+	}
+
+	return nil
 }
 
 func (i *Indexer) ErrorHandler(ctx *macaron.Context) {
@@ -392,16 +523,16 @@ func (i *Indexer) getSession(id string) *session {
 func (i *Indexer) requireSession(ctx *macaron.Context, project *project, version string) (*session, error) {
 	id := ctx.GetCookie(sessionCookieName)
 	if id == "" {
-		return nil, httperror.Forbidden("Missing session")
+		return nil, httperror.Problem(http.StatusForbidden, "missing-session", "Missing session", nil)
 	}
 
 	s := i.getSession(id)
 	if s == nil {
-		return nil, httperror.Forbidden("Unknown session")
+		return nil, httperror.Problem(http.StatusForbidden, "unknown-session", "Unknown session", nil)
 	}
 
-	if s.project != project || (version != "" && s.version != version) {
-		return s, httperror.BadRequest("Invalid session provided", nil)
+	if s.project.id != project.id || (version != "" && s.version != version) {
+		return s, httperror.Problem(http.StatusBadRequest, "invalid-session", "Invalid session provided", nil)
 	}
 
 	s.lock(ctx)
@@ -421,7 +552,13 @@ func (i *Indexer) getOrCreateSession(ctx *macaron.Context, project *project, ver
 	}
 
 	if project.useSemVer && !semVerPattern.MatchString(version) {
-		return nil, httperror.BadRequest("Version does not match semantic versioning specifications", nil)
+		return nil, httperror.Problem(http.StatusBadRequest, "invalid-version",
+			"Version does not match semantic versioning specifications", httperror.Details{"version": version})
+	}
+
+	dbID, err := i.persistSession(project, version)
+	if err != nil {
+		return nil, err
 	}
 
 	sessionID := string(com.RandomCreateBytes(sessionSecretLength))
@@ -429,6 +566,7 @@ func (i *Indexer) getOrCreateSession(ctx *macaron.Context, project *project, ver
 
 	s = &session{
 		id:        sessionID,
+		dbID:      dbID,
 		project:   project,
 		version:   version,
 		artifacts: make(map[artifactType]*artifact),
@@ -441,6 +579,7 @@ func (i *Indexer) getOrCreateSession(ctx *macaron.Context, project *project, ver
 
 	// Start goroutine which will destroy the session after timeout
 	go s.destroy(i)
+	metrics.ActiveSessions.Inc()
 
 	i.registerSession(s)
 
@@ -455,6 +594,9 @@ func (s *session) lock(ctx *macaron.Context) {
 
 	s._lock.Lock()
 	s.timeout.Stop()
+
+	// The logger is used by the request holding the lock, so it can be only replaced afterwards
+	s.log = downloads.RequestLogger(ctx).WithField("session", s.dbID)
 }
 
 func (s *session) unlock() {
@@ -466,7 +608,7 @@ func (s *session) release(i *Indexer) {
 	if s.tx != nil {
 		err := s.tx.Rollback()
 		if err != nil {
-			i.Log.Println("Failed to rollback transaction:", err)
+			s.log.Errorln("Failed to rollback transaction:", err)
 		}
 
 		s.tx = nil
@@ -479,10 +621,45 @@ func (s *session) release(i *Indexer) {
 	}
 }
 
+// commit commits the transaction of the session and publishes the staged
+// files. The files are published before the project is unlocked, otherwise the
+// metadata of a session committed later could be overwritten with older metadata.
+func (s *session) commit(i *Indexer) error {
+	err := s.markCommitted()
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	s.tx = nil
+	s.committed = true
+
+	err = i.publish(s.log, s.dbID, s.staged)
+	s.release(i)
+	if err != nil {
+		// The session is committed, the files are published again when the session is finished
+		return err
+	}
+
+	go i.projectUpdated(s.project.id)
+	return nil
+}
+
 func (s *session) fail(i *Indexer) {
 	if !s.failed {
 		s.failed = true
 		s.release(i)
+
+		if !s.committed {
+			err := i.discardStaged(s.dbID)
+			if err != nil {
+				s.log.Errorln("Failed to discard staged files:", err)
+			}
+		}
 	}
 }
 
@@ -492,8 +669,27 @@ func (s *session) destroy(i *Indexer) {
 	s.release(i)
 
 	i.sessionLock.Lock()
-	defer i.sessionLock.Unlock()
 	delete(i.sessions, s.id)
+	i.sessionLock.Unlock()
+
+	metrics.ActiveSessions.Dec()
+	metrics.Sessions.WithLabelValues(s.outcome()).Inc()
+
+	err := i.finishSession(s.dbID)
+	if err != nil {
+		s.log.Errorln("Failed to finish session:", err)
+	}
+}
+
+func (s *session) outcome() string {
+	switch {
+	case s.committed:
+		return "committed"
+	case s.failed:
+		return "failed"
+	default:
+		return "expired"
+	}
 }
 
 func decodeHash(data []byte) string {
@@ -0,0 +1,191 @@
+package indexer
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckContentLength(t *testing.T) {
+	i := &Indexer{MaxFileSize: 100}
+
+	tests := []struct {
+		name     string
+		length   int64
+		encoding []string
+		code     int
+	}{
+		{"ok", 50, nil, http.StatusOK},
+		{"max size", 100, nil, http.StatusOK},
+		{"chunked", -1, []string{"chunked"}, http.StatusLengthRequired},
+		{"missing", -1, nil, http.StatusLengthRequired},
+		{"empty", 0, nil, http.StatusLengthRequired},
+		{"too large", 101, nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{ContentLength: test.length, TransferEncoding: test.encoding}
+
+			code := http.StatusOK
+			if err := i.checkContentLength(req); err != nil {
+				httpErr, ok := err.(*httperror.HTTPError)
+				if !ok {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				code = httpErr.Code
+			}
+
+			if code != test.code {
+				t.Errorf("got status %d, expected %d", code, test.code)
+			}
+		})
+	}
+}
+
+func TestConcurrentCommits(t *testing.T) {
+	database, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer database.Close()
+
+	stagingDir, err := ioutil.TempDir("", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(stagingDir)
+
+	repo := &recordingRepository{files: make(map[string]string), delay: make(map[string]time.Duration)}
+	i := &Indexer{
+		Module:     (&downloads.Manager{DB: database}).Module("Test"),
+		repo:       repo,
+		StagingDir: stagingDir,
+	}
+
+	p := &project{id: 1, lock: new(sync.Mutex)}
+	path := metadataPath(maven.Identifier{GroupID: "org.example", ArtifactID: "example"}, "")
+
+	// The first session publishes slowly, the second one waits for the project lock
+	repo.delay[path] = 100 * time.Millisecond
+
+	start := func(id int, metadata string) *session {
+		p.lock.Lock()
+
+		s := &session{dbID: id, log: i.Log, project: p, lockedProject: true}
+		s.tx, err = database.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = i.stageBytes(s, path, []byte(metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	first := start(1, "first")
+
+	done := make(chan error)
+	go func() {
+		done <- first.commit(i)
+	}()
+
+	second := start(2, "second")
+	repo.setDelay(path, 0)
+
+	err = second.commit(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata := repo.get(path); metadata != "second" {
+		t.Errorf("published metadata of session %q, expected the one committed last", metadata)
+	}
+}
+
+// recordingRepository stores uploaded files in memory and delays uploads of some paths.
+type recordingRepository struct {
+	maven.Repository
+
+	files map[string]string
+	delay map[string]time.Duration
+	lock  sync.Mutex
+}
+
+func (repo *recordingRepository) Upload(path string, reader io.Reader, len int64) error {
+	repo.lock.Lock()
+	delay := repo.delay[path]
+	repo.lock.Unlock()
+
+	time.Sleep(delay)
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+	repo.files[path] = string(data)
+	return nil
+}
+
+func (repo *recordingRepository) setDelay(path string, delay time.Duration) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+	repo.delay[path] = delay
+}
+
+func (repo *recordingRepository) get(path string) string {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+	return repo.files[path]
+}
+
+// fakeDriver is a database driver that accepts all statements and returns no rows.
+type fakeDriver struct{}
+
+func init() {
+	sql.Register("fake", fakeDriver{})
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeConn{}, nil }
+func (fakeConn) Commit() error                             { return nil }
+func (fakeConn) Rollback() error                           { return nil }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return nil }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
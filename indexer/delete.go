@@ -0,0 +1,197 @@
+package indexer
+
+import (
+	"database/sql"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var metadataChecksumExtensions = []string{md5Extension, sha1Extension, sha256Extension, sha512Extension}
+
+// DeleteVersion deletes a download using the function and removes its files
+// from the Maven repository afterwards. The metadata is generated again without
+// the deleted version. The project is locked, so no uploads are published at
+// the same time.
+func (i *Indexer) DeleteVersion(identifier maven.Identifier, version string, deleteIndexed func() error) error {
+	p := i.getProject(identifier)
+	if p == nil {
+		return httperror.NotFound("Unknown project")
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var snapshotVersion sql.NullString
+	err := i.DB.QueryRow("SELECT snapshot_version FROM downloads WHERE project_id = $1 AND version = $2;",
+		p.id, version).Scan(&snapshotVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return httperror.Problem(http.StatusNotFound, "unknown-version", "Unknown version", nil)
+		}
+		return httperror.InternalError("Database error (failed to lookup download)", err)
+	}
+
+	err = deleteIndexed()
+	if err != nil {
+		return err
+	}
+
+	// Snapshot builds share the directory with the other builds of the snapshot version
+	dir := version
+	if snapshotVersion.Valid {
+		dir = snapshotVersion.String
+	}
+
+	err = i.deleteFiles(identifier, dir, version)
+	if err != nil {
+		return err
+	}
+
+	s := &session{project: p}
+
+	if snapshotVersion.Valid {
+		err = i.publishMetadata(s, identifier, dir, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.publishMetadata(s, identifier, "", dir)
+}
+
+// deleteFiles deletes the files of the version from the directory in the repository.
+func (i *Indexer) deleteFiles(identifier maven.Identifier, dir string, version string) error {
+	path := strings.Replace(identifier.GroupID, ".", "/", -1) + "/" + identifier.ArtifactID + "/" + dir + "/"
+
+	files, err := i.repo.List(path)
+	if err != nil {
+		if httperror.IsNotFound(err) {
+			return nil
+		}
+		return httperror.InternalError("Failed to list files of version", err)
+	}
+
+	prefix := identifier.ArtifactID + "-" + version
+	for _, f := range files {
+		// The prefix must be followed by the classifier or extension (e.g. -1 should not match -10)
+		if f.Dir || !strings.HasPrefix(f.Name, prefix) || len(f.Name) == len(prefix) ||
+			(f.Name[len(prefix)] != '-' && f.Name[len(prefix)] != '.') {
+			continue
+		}
+
+		err = i.repo.Delete(path + f.Name)
+		if err != nil {
+			return httperror.InternalError("Failed to delete file", err)
+		}
+	}
+
+	return nil
+}
+
+// publishMetadata generates the project metadata or the snapshot metadata if
+// the version is set and uploads it to the repository. The removed version is
+// excluded from the project metadata if it is not indexed anymore, even if it
+// is listed in the existing metadata. If there are no versions left, the
+// metadata is deleted.
+func (i *Indexer) publishMetadata(s *session, identifier maven.Identifier, version string, removed string) error {
+	path := metadataPath(identifier, version)
+
+	m, err := i.generateMetadata(s, identifier, version)
+	if err != nil && !httperror.IsNotFound(err) {
+		return err
+	}
+
+	if m != nil && removed != "" {
+		var indexed bool
+		err = i.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM downloads WHERE project_id = $1 AND "+
+			"coalesce(snapshot_version, version) = $2);", s.project.id, removed).Scan(&indexed)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to lookup download)", err)
+		}
+
+		if !indexed {
+			m.remove(removed)
+		}
+	}
+
+	if m == nil || (version == "" && len(m.Versioning.Versions) == 0) {
+		for _, extension := range append([]string{""}, metadataChecksumExtensions...) {
+			err = i.repo.Delete(path + extension)
+			if err != nil {
+				return httperror.InternalError("Failed to delete metadata", err)
+			}
+		}
+
+		return nil
+	}
+
+	data, err := m.marshal()
+	if err != nil {
+		return httperror.InternalError("Failed to generate metadata", err)
+	}
+
+	u, err := i.receive(func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return httperror.InternalError("Failed to generate metadata", err)
+	}
+
+	defer u.remove()
+
+	f, err := u.open()
+	if err != nil {
+		return httperror.InternalError("Failed to open metadata", err)
+	}
+
+	defer f.Close()
+
+	err = i.repo.Upload(path, f, u.size)
+	if err != nil {
+		return err
+	}
+
+	checksums := []string{u.md5, u.sha1, u.sha256, u.sha512}
+	for n, extension := range metadataChecksumExtensions {
+		err = i.repo.Upload(path+extension, strings.NewReader(checksums[n]), int64(len(checksums[n])))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove removes the version from the metadata. If it was the latest or release
+// version, the last remaining (release) version is used instead.
+func (m *mavenMetadata) remove(version string) {
+	var versions []string
+	for _, v := range m.Versioning.Versions {
+		if v != version {
+			versions = append(versions, v)
+		}
+	}
+
+	m.Versioning.Versions = versions
+
+	if m.Versioning.Latest == version {
+		m.Versioning.Latest = ""
+		if len(versions) > 0 {
+			m.Versioning.Latest = versions[len(versions)-1]
+		}
+	}
+
+	if m.Versioning.Release == version {
+		m.Versioning.Release = ""
+		for n := len(versions) - 1; n >= 0; n-- {
+			if !strings.HasSuffix(versions[n], snapshotSuffix) {
+				m.Versioning.Release = versions[n]
+				break
+			}
+		}
+	}
+}
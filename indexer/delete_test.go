@@ -0,0 +1,31 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadataRemove(t *testing.T) {
+	m := new(mavenMetadata)
+	m.Versioning.Versions = []string{"1.0", "1.1", "2.0-SNAPSHOT", "2.0"}
+	m.Versioning.Latest = "2.0"
+	m.Versioning.Release = "2.0"
+
+	m.remove("2.0")
+
+	if expected := []string{"1.0", "1.1", "2.0-SNAPSHOT"}; !reflect.DeepEqual(m.Versioning.Versions, expected) {
+		t.Errorf("got versions %v, expected %v", m.Versioning.Versions, expected)
+	}
+	if m.Versioning.Latest != "2.0-SNAPSHOT" {
+		t.Errorf("got latest %q, expected 2.0-SNAPSHOT", m.Versioning.Latest)
+	}
+	if m.Versioning.Release != "1.1" {
+		t.Errorf("got release %q, expected 1.1", m.Versioning.Release)
+	}
+
+	m.remove("1.0")
+
+	if m.Versioning.Latest != "2.0-SNAPSHOT" || m.Versioning.Release != "1.1" {
+		t.Errorf("latest and release should not change when removing another version")
+	}
+}
@@ -0,0 +1,90 @@
+package forge
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"io"
+	"strings"
+)
+
+const (
+	// MetadataFileName is the mod metadata file used by Forge for Minecraft 1.13 and later
+	MetadataFileName = "META-INF/mods.toml"
+
+	// JarVersion is replaced with the Implementation-Version from the manifest by Forge
+	JarVersion = "${file.jarVersion}"
+)
+
+type metadata struct {
+	Mods         []mod                   `toml:"mods"`
+	Dependencies map[string][]dependency `toml:"dependencies"`
+}
+
+type mod struct {
+	ID          string `toml:"modId"`
+	Name        string `toml:"displayName"`
+	Version     string `toml:"version"`
+	Description string `toml:"description"`
+	URL         string `toml:"displayURL"`
+	Authors     string `toml:"authors"`
+}
+
+type dependency struct {
+	ID           string `toml:"modId"`
+	Mandatory    bool   `toml:"mandatory"`
+	VersionRange string `toml:"versionRange"`
+	Ordering     string `toml:"ordering"`
+}
+
+// ReadMetadata reads the mods from a mods.toml file.
+func ReadMetadata(reader io.Reader) ([]*mcmod.Metadata, error) {
+	var meta metadata
+	_, err := toml.DecodeReader(reader, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*mcmod.Metadata, len(meta.Mods))
+	for i, m := range meta.Mods {
+		result[i] = &mcmod.Metadata{
+			ID:          m.ID,
+			Name:        m.Name,
+			Version:     m.Version,
+			Description: strings.TrimSpace(m.Description),
+			URL:         m.URL,
+		}
+
+		// Forge only has a single string for all authors
+		if m.Authors != "" {
+			result[i].Authors = []string{m.Authors}
+		}
+
+		for _, d := range meta.Dependencies[m.ID] {
+			// Forge uses * for "any version"
+			version := d.VersionRange
+			if version == "*" {
+				version = ""
+			}
+
+			result[i].Dependencies = append(result[i].Dependencies, mcmod.Dependency{
+				ID:        d.ID,
+				Version:   version,
+				Required:  d.Mandatory,
+				LoadOrder: loadOrder(d.Ordering),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func loadOrder(ordering string) string {
+	switch ordering {
+	case "AFTER":
+		return mcmod.LoadAfter
+	case "BEFORE":
+		return mcmod.LoadBefore
+	default:
+		return "" // NONE
+	}
+}
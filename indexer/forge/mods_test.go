@@ -0,0 +1,91 @@
+package forge
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+	const input = `
+modLoader = "javafml"
+loaderVersion = "[31,)"
+license = "MIT"
+
+[[mods]]
+modId = "example"
+version = "${file.jarVersion}"
+displayName = "Example"
+displayURL = "https://example.com"
+authors = "Alice, Bob"
+description = '''
+An example mod
+'''
+
+[[mods]]
+modId = "addon"
+version = "2.0.0"
+
+[[dependencies.example]]
+modId = "forge"
+mandatory = true
+versionRange = "[31,)"
+ordering = "NONE"
+side = "BOTH"
+
+[[dependencies.example]]
+modId = "minecraft"
+mandatory = true
+versionRange = "*"
+ordering = "AFTER"
+
+[[dependencies.example]]
+modId = "other"
+mandatory = false
+versionRange = "[1.0,2.0)"
+ordering = "BEFORE"
+`
+
+	expected := []*mcmod.Metadata{
+		{
+			ID:          "example",
+			Name:        "Example",
+			Version:     JarVersion,
+			Description: "An example mod",
+			URL:         "https://example.com",
+			Authors:     []string{"Alice, Bob"},
+			Dependencies: []mcmod.Dependency{
+				{ID: "forge", Version: "[31,)", Required: true},
+				{ID: "minecraft", Required: true, LoadOrder: mcmod.LoadAfter},
+				{ID: "other", Version: "[1.0,2.0)", LoadOrder: mcmod.LoadBefore},
+			},
+		},
+		{
+			ID:      "addon",
+			Version: "2.0.0",
+		},
+	}
+
+	mods, err := ReadMetadata(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mods) != len(expected) {
+		t.Fatalf("got %d mods, expected %d", len(mods), len(expected))
+	}
+
+	for i, mod := range mods {
+		if !reflect.DeepEqual(mod, expected[i]) {
+			t.Errorf("mod %d: got %+v, expected %+v", i, mod, expected[i])
+		}
+	}
+}
+
+func TestReadMetadataInvalid(t *testing.T) {
+	_, err := ReadMetadata(strings.NewReader(`[[mods]`))
+	if err == nil {
+		t.Error("expected error for invalid TOML")
+	}
+}
@@ -0,0 +1,120 @@
+package gradle
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Gradle Module Metadata, see https://github.com/gradle/gradle/blob/master/subprojects/docs/src/docs/design/gradle-module-metadata-latest-specification.md
+
+const (
+	ModuleExtension = "module"
+	formatVersion   = "1."
+)
+
+type Module struct {
+	FormatVersion string     `json:"formatVersion"`
+	Component     Component  `json:"component"`
+	Variants      []*Variant `json:"variants"`
+}
+
+type Component struct {
+	Group   string `json:"group"`
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+type Variant struct {
+	Name         string                 `json:"name"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Capabilities []Capability           `json:"capabilities,omitempty"`
+	Dependencies []Dependency           `json:"dependencies,omitempty"`
+	// Constraints for the versions of transitive dependencies
+	DependencyConstraints []DependencyConstraint `json:"dependencyConstraints,omitempty"`
+	Files                 []File                 `json:"files,omitempty"`
+
+	// Variants that are available in another module (e.g. platform specific variants)
+	AvailableAt *AvailableAt `json:"available-at,omitempty"`
+}
+
+type Capability struct {
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type Dependency struct {
+	Group   string             `json:"group"`
+	Module  string             `json:"module"`
+	Version *VersionConstraint `json:"version,omitempty"`
+	Reason  string             `json:"reason,omitempty"`
+
+	Attributes            map[string]interface{} `json:"attributes,omitempty"`
+	RequestedCapabilities []Capability           `json:"requestedCapabilities,omitempty"`
+
+	Excludes []Exclude `json:"excludes,omitempty"`
+	// EndorseStrictVersions applies the strict versions of the dependency to the consumer
+	EndorseStrictVersions bool `json:"endorseStrictVersions,omitempty"`
+}
+
+// Exclude excludes transitive dependencies, * matches any group or module.
+type Exclude struct {
+	Group  string `json:"group"`
+	Module string `json:"module"`
+}
+
+type DependencyConstraint struct {
+	Group   string             `json:"group"`
+	Module  string             `json:"module"`
+	Version *VersionConstraint `json:"version,omitempty"`
+	Reason  string             `json:"reason,omitempty"`
+
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// File is a file of the variant. The URL is relative to the module metadata.
+type File struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA1   string `json:"sha1,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	SHA512 string `json:"sha512,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+}
+
+type VersionConstraint struct {
+	Requires string   `json:"requires,omitempty"`
+	Strictly string   `json:"strictly,omitempty"`
+	Prefers  string   `json:"prefers,omitempty"`
+	Rejects  []string `json:"rejects,omitempty"`
+}
+
+type AvailableAt struct {
+	URL     string `json:"url"`
+	Group   string `json:"group"`
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+func ReadModule(reader io.Reader) (*Module, error) {
+	m := new(Module)
+	err := json.NewDecoder(reader).Decode(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(m.FormatVersion, formatVersion) {
+		return nil, errors.New("Unsupported module format version: " + m.FormatVersion)
+	}
+
+	for _, v := range m.Variants {
+		if v.Name == "" {
+			return nil, errors.New("Variant without name")
+		}
+	}
+
+	return m, nil
+}
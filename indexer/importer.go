@@ -0,0 +1,334 @@
+package indexer
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/gradle"
+	"github.com/SpongePowered/DownloadIndexer/indexer/pom"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ImportOptions struct {
+	// DryRun indexes all versions in a single transaction that is rolled back
+	// afterwards, so the changelogs are generated like for a real import
+	DryRun bool
+	// Resume skips versions that are already indexed (instead of failing)
+	Resume bool
+
+	// Classifiers of the side artifacts that are imported (if they exist)
+	Classifiers []string
+
+	// Override the build type and branch (otherwise read from the manifest)
+	BuildType string
+	Branch    string
+
+	// RequireChangelog fails the import if the changelog cannot be generated
+	RequireChangelog bool
+}
+
+type importVersion struct {
+	version        string
+	displayVersion string
+	snapshot       bool
+
+	mainJar   *upload
+	published time.Time
+}
+
+// Import indexes all versions of the project that exist in the specified Maven
+// repository (e.g. to rebuild the database). The versions are indexed in the
+// order they were published, so the changelogs are generated correctly.
+func (i *Indexer) Import(source maven.Repository, identifier maven.Identifier, opts *ImportOptions) error {
+	project := i.getProject(identifier)
+	if project == nil {
+		return errors.New("Unknown project: " + identifier.GroupID + ":" + identifier.ArtifactID)
+	}
+
+	// Prevent concurrent imports or uploads of the project in this process. The
+	// lock is not shared with other processes (e.g. the import command runs
+	// separately from the server), so uploads should be paused while importing.
+	project.lock.Lock()
+	defer project.lock.Unlock()
+
+	basePath := strings.Replace(identifier.GroupID, ".", "/", -1) + "/" + identifier.ArtifactID + "/"
+
+	metadata, err := downloadMetadata(source, basePath+mavenMetadataFile)
+	if err != nil {
+		return err
+	}
+
+	existing, err := i.indexedVersions(project)
+	if err != nil {
+		return err
+	}
+
+	// Download all main JARs first to find out in which order they were published
+	var versions []*importVersion
+	defer func() {
+		for _, v := range versions {
+			v.mainJar.remove()
+		}
+	}()
+
+	for _, version := range metadata.Versioning.Versions {
+		v := &importVersion{version: version, displayVersion: version}
+
+		if strings.HasSuffix(version, snapshotSuffix) {
+			if !project.useSnapshots {
+				i.Log.Println("Skipping", version, "(project does not use snapshots)")
+				continue
+			}
+
+			v.snapshot = true
+			v.displayVersion, err = resolveSnapshot(source, basePath+version+"/"+mavenMetadataFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		if existing[v.displayVersion] {
+			if !opts.Resume {
+				return errors.New(v.displayVersion + " is already indexed")
+			}
+
+			i.Log.Println("Skipping", v.displayVersion, "(already indexed)")
+			continue
+		}
+
+		v.mainJar, err = i.download(source, v.artifactPath(basePath, identifier.ArtifactID, artifactType{"", jarExtension}))
+		if err != nil {
+			return err
+		}
+
+		versions = append(versions, v)
+
+		_, v.published, _, err = readJar(v.mainJar.path, false)
+		if err != nil {
+			return errors.New("Failed to read JAR file of " + v.displayVersion + ": " + err.Error())
+		}
+	}
+
+	sort.SliceStable(versions, func(a, b int) bool {
+		return versions[a].published.Before(versions[b].published)
+	})
+
+	var dryRunTx *sql.Tx
+	if opts.DryRun {
+		dryRunTx, err = i.DB.Begin()
+		if err != nil {
+			return err
+		}
+
+		defer dryRunTx.Rollback()
+	}
+
+	for _, v := range versions {
+		err = i.importVersion(source, project, identifier, basePath, v, dryRunTx, opts)
+		if err != nil {
+			return errors.New("Failed to import " + v.displayVersion + ": " + err.Error())
+		}
+	}
+
+	if !opts.DryRun && len(versions) > 0 {
+		_, err = i.DB.Exec("UPDATE projects SET last_updated = current_timestamp WHERE project_id = $1;", project.id)
+		if err != nil {
+			return err
+		}
+
+		if i.Cache != nil {
+			i.Cache.PurgeProject(identifier)
+		}
+	}
+
+	i.Log.Println("Imported", len(versions), "versions of", identifier.GroupID+":"+identifier.ArtifactID)
+	return nil
+}
+
+// importVersion indexes the version with its side artifacts, POM and Gradle
+// module metadata. Dry runs use the transaction, so later versions are
+// indexed based on the earlier ones.
+func (i *Indexer) importVersion(source maven.Repository, project *project, identifier maven.Identifier,
+	basePath string, v *importVersion, dryRunTx *sql.Tx, opts *ImportOptions) error {
+
+	s := &session{
+		log:       i.Log.WithField("version", v.displayVersion),
+		project:   project,
+		version:   v.version,
+		artifacts: make(map[artifactType]*artifact),
+		tx:        dryRunTx,
+	}
+
+	// Rollback transaction if the import fails
+	defer s.release(i)
+
+	err := s.createDownload(i, v.displayVersion, v.mainJar, opts.BuildType, opts.Branch, nil, nullTime,
+		v.snapshot, opts.RequireChangelog)
+	if err != nil {
+		return err
+	}
+
+	mainJar := artifactType{"", jarExtension}
+	types := []artifactType{mainJar}
+	for _, classifier := range opts.Classifiers {
+		types = append(types, artifactType{classifier, jarExtension})
+	}
+
+	// The POM and the Gradle module metadata are indexed as well (if they exist)
+	types = append(types, artifactType{"", pom.Extension}, artifactType{"", gradle.ModuleExtension})
+
+	for _, t := range types {
+		filePath := v.artifactPath(basePath, identifier.ArtifactID, t)
+
+		u := v.mainJar
+		if t != mainJar {
+			u, err = i.download(source, filePath)
+			if err != nil {
+				if httperror.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+
+			defer u.remove()
+		}
+
+		a := new(artifact)
+		err = verifyChecksums(source, filePath, a)
+		if err != nil {
+			return err
+		}
+
+		module := t.extension == gradle.ModuleExtension
+		err = a.create(s, t, u, t.extension == jarExtension || module)
+		if err != nil {
+			return err
+		}
+
+		p := path{Identifier: identifier, version: v.version, displayVersion: v.displayVersion, artifact: t}
+		switch t.extension {
+		case gradle.ModuleExtension:
+			err = s.indexModule(p, u)
+		case pom.Extension:
+			err = s.indexPOM(p, u)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.DryRun {
+		// Keep the transaction for the next versions, it is rolled back after the import
+		s.tx = nil
+		i.Log.Println("Would import", v.displayVersion, "published", v.published)
+		return nil
+	}
+
+	err = s.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	s.tx = nil
+	i.Log.Println("Imported", v.displayVersion, "published", v.published)
+	return nil
+}
+
+func (i *Indexer) indexedVersions(p *project) (map[string]bool, error) {
+	rows, err := i.DB.Query("SELECT version FROM downloads WHERE project_id = $1;", p.id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, err
+		}
+
+		result[version] = true
+	}
+
+	return result, rows.Err()
+}
+
+func (v *importVersion) artifactPath(basePath, artifactID string, t artifactType) string {
+	path := basePath + v.version + "/" + artifactID + "-" + v.displayVersion
+	if t.classifier != "" {
+		path += "-" + t.classifier
+	}
+	return path + "." + t.extension
+}
+
+func downloadMetadata(source maven.Repository, path string) (*mavenMetadata, error) {
+	var buf bytes.Buffer
+	err := source.Download(path, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := new(mavenMetadata)
+	err = xml.Unmarshal(buf.Bytes(), metadata)
+	if err != nil {
+		return nil, errors.New("Failed to parse " + path + ": " + err.Error())
+	}
+
+	return metadata, nil
+}
+
+// resolveSnapshot returns the version of the latest snapshot build from the snapshot metadata.
+func resolveSnapshot(source maven.Repository, path string) (string, error) {
+	metadata, err := downloadMetadata(source, path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range metadata.Versioning.SnapshotVersions {
+		if v.Classifier == "" && v.Extension == jarExtension {
+			return v.Value, nil
+		}
+	}
+
+	return "", errors.New("Missing snapshot version in " + path)
+}
+
+func (i *Indexer) download(source maven.Repository, path string) (*upload, error) {
+	return i.receive(func(w io.Writer) error {
+		return source.Download(path, w)
+	})
+}
+
+// verifyChecksums downloads the checksum files of the artifact so they are verified when creating the artifact.
+func verifyChecksums(source maven.Repository, path string, a *artifact) error {
+	checksums := map[string]*string{
+		md5Extension:    &a.md5,
+		sha1Extension:   &a.sha1,
+		sha256Extension: &a.sha256,
+		sha512Extension: &a.sha512,
+	}
+
+	var buf bytes.Buffer
+	for extension, checksum := range checksums {
+		buf.Reset()
+
+		err := source.Download(path+extension, &buf)
+		if err == nil {
+			*checksum = decodeHash(buf.Bytes())
+		} else if !httperror.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,14 +1,16 @@
 package indexer
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/forge"
 	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"github.com/SpongePowered/DownloadIndexer/indexer/recommend"
+	"github.com/lib/pq"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -18,17 +20,16 @@ const (
 	remoteOriginPrefix = "origin" + string(remoteSeparator)
 	buildTypeSeparator = '-'
 
-	emptyChangelog   = "[]"
-	recommendedLabel = "recommended"
+	emptyChangelog = "[]"
 )
 
 var nullTime = time.Time{}
 
-func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []byte,
+func (s *session) createDownload(i *Indexer, displayVersion string, mainJar *upload,
 	buildType, branch string, metadataBytes []byte, publishedOverride time.Time,
-	recommended, requireChangelog bool) error {
+	snapshot, requireChangelog bool) error {
 
-	manifest, published, metadata, err := readJar(mainJar, s.project.pluginID != "")
+	manifest, published, metadata, err := readJar(mainJar.path, s.project.pluginID != "")
 	if err != nil {
 		return httperror.BadRequest("Failed to read JAR file", err)
 	}
@@ -36,6 +37,10 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 		return httperror.BadRequest("Missing manifest in JAR", nil)
 	}
 
+	for _, manifestErr := range manifest.Errors {
+		s.log.Warnln("Skipped line in manifest:", manifestErr)
+	}
+
 	if publishedOverride != nullTime {
 		published = publishedOverride
 	} else if published == nullTime {
@@ -43,37 +48,63 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 	}
 
 	if metadataBytes != nil {
-		metadata, err = mcmod.ReadMetadataBytes(metadataBytes)
+		plugins, err := mcmod.ReadMetadataBytes(metadataBytes)
 		if err != nil {
 			return httperror.BadRequest("Failed to read metadata file", err)
 		}
+
+		metadata = []*pluginMetadata{{fileName: mcmod.MetadataFileName, plugins: plugins}}
 	}
 
+	// Use the first metadata file that contains the plugin
 	var pluginMeta *mcmod.Metadata
-	if metadata != nil {
-		for _, metaEntry := range metadata {
-			if metaEntry.ID == s.project.pluginID {
-				pluginMeta = metaEntry
-				break
+	var metadataFile string
+	var metadataErr error
+	for _, m := range metadata {
+		if m.err != nil {
+			s.log.Warnln("Failed to read", m.fileName+":", m.err)
+			if metadataErr == nil {
+				metadataErr = m.err
 			}
+			continue
+		}
+
+		if pluginMeta = m.find(s.project.pluginID); pluginMeta != nil {
+			metadataFile = m.fileName
+			break
+		}
+	}
+
+	if pluginMeta != nil {
+		if pluginMeta.Version == forge.JarVersion {
+			pluginMeta.Version = manifest.Main["Implementation-Version"]
 		}
 
-		if pluginMeta == nil {
-			return httperror.BadRequest("Missing plugin '"+s.project.pluginID+"' in "+mcmod.MetadataFileName, nil)
-		} else if pluginMeta.Version != s.version {
-			return httperror.BadRequest(mcmod.MetadataFileName+" version mismatch: "+s.version+" != "+pluginMeta.Version, nil)
+		if pluginMeta.Version != s.version {
+			return httperror.Problem(http.StatusBadRequest, "version-mismatch",
+				metadataFile+" version mismatch: "+s.version+" != "+pluginMeta.Version,
+				httperror.Details{"expected": s.version, "actual": pluginMeta.Version})
 		}
+	} else if metadataErr != nil {
+		return httperror.BadRequest("Failed to read metadata file", metadataErr)
+	} else if len(metadata) > 0 {
+		fileNames := make([]string, len(metadata))
+		for i, m := range metadata {
+			fileNames[i] = m.fileName
+		}
+
+		return httperror.BadRequest("Missing plugin '"+s.project.pluginID+"' in "+strings.Join(fileNames, ", "), nil)
 	} else if s.project.pluginID != "" {
-		return httperror.BadRequest("Missing "+mcmod.MetadataFileName+" in JAR", nil)
+		return httperror.BadRequest("Missing plugin metadata in JAR (expected one of "+metadataFileNames()+")", nil)
 	}
 
-	commit := manifest["Git-Commit"]
+	commit := manifest.Main["Git-Commit"]
 	if commit == "" {
 		return httperror.BadRequest("Missing Git-Commit in manifest", err)
 	}
 
 	if branch == "" {
-		branch = manifest["Git-Branch"]
+		branch = manifest.Main["Git-Branch"]
 		if branch == "" {
 			return httperror.BadRequest("Missing Git-Branch in manifest", err)
 		}
@@ -97,19 +128,26 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 		s.project.id, buildType).Scan(&buildTypeID, &allowsPromotion)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return httperror.BadRequest("Unknown build type", err)
+			return httperror.Problem(http.StatusBadRequest, "unknown-build-type", "Unknown build type",
+				httperror.Details{"buildType": buildType})
 		}
 		return httperror.InternalError("Database error (failed to lookup build type)", err)
 	}
 
-	if recommended && !allowsPromotion {
-		return httperror.BadRequest("Build type does not allow promotion", err)
-	}
-
-	// Start transaction
-	s.tx, err = i.DB.Begin()
-	if err != nil {
-		return httperror.InternalError("Database error (failed to start transaction)", err)
+	// Only builds of build types that allow promotion can be recommended
+	recommended := allowsPromotion && s.project.recommendation.Recommend(&recommend.Build{
+		Version:   displayVersion,
+		Snapshot:  snapshot,
+		BuildType: buildType,
+		Manifest:  manifest.Main,
+	})
+
+	// Start transaction (unless the session already has one, e.g. for dry runs of the import)
+	if s.tx == nil {
+		s.tx, err = i.DB.Begin()
+		if err != nil {
+			return httperror.InternalError("Database error (failed to start transaction)", err)
+		}
 	}
 
 	var changelog string
@@ -118,15 +156,15 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 	if buildTypeID > 0 {
 		var parentCommit string
 		err = s.tx.QueryRow("SELECT commit FROM downloads "+
-			"WHERE project_id = $1 AND build_type_id = $2 ORDER BY published DESC LIMIT 1;",
-			s.project.id, buildTypeID).Scan(&parentCommit)
+			"WHERE project_id = $1 AND build_type_id = $2 AND published < $3 ORDER BY published DESC LIMIT 1;",
+			s.project.id, buildTypeID, published).Scan(&parentCommit)
 		if err != nil && err != sql.ErrNoRows {
 			return httperror.InternalError("Database error (failed to lookup parent commit)", err)
 		}
 
 		if parentCommit != "" {
 			// Parent commit found, generate changelog
-			changelog, err = i.generateChangelog(s.project, commit, parentCommit, requireChangelog)
+			changelog, err = s.generateChangelog(i, commit, parentCommit, requireChangelog)
 			if err != nil {
 				return err
 			}
@@ -135,7 +173,7 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 
 	label := ""
 	if recommended {
-		label = recommendedLabel
+		label = downloads.RecommendedLabel
 	}
 
 	snapshotVersion := s.version
@@ -151,18 +189,33 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 		return httperror.InternalError("Database error (failed to add download)", err)
 	}
 
-	// Insert dependencies (if available)
+	// Insert plugin metadata and dependencies (if available)
 	if pluginMeta != nil {
+		var authors interface{}
+		if len(pluginMeta.Authors) > 0 {
+			authors = pq.Array(pluginMeta.Authors)
+		}
+
+		_, err = s.tx.Exec("UPDATE downloads SET plugin_name = $1, plugin_description = $2, plugin_url = $3, "+
+			"plugin_authors = $4 WHERE download_id = $5;",
+			db.ToNullString(pluginMeta.Name), db.ToNullString(pluginMeta.Description), db.ToNullString(pluginMeta.URL),
+			authors, s.downloadID)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to add plugin metadata)", err)
+		}
+
 		for _, dependency := range pluginMeta.Dependencies {
 			dependency.Version = cleanVersion(dependency.Version)
 			if dependency.Version != "" {
-				_, err = s.tx.Exec("INSERT INTO dependencies VALUES ($1, $2, $3);",
-					s.downloadID, dependency.ID, dependency.Version)
+				_, err = s.tx.Exec("INSERT INTO dependencies (download_id, name, version, required, load_order) "+
+					"VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING;",
+					s.downloadID, dependency.ID, dependency.Version, dependency.Required,
+					db.ToNullString(dependency.LoadOrder))
 				if err != nil {
 					return httperror.InternalError("Database error (failed to add dependency)", err)
 				}
 			} else {
-				i.Log.Println("Skipping dependency", dependency.ID, "(missing version)")
+				s.log.Println("Skipping dependency", dependency.ID, "(missing version)")
 			}
 		}
 	}
@@ -170,14 +223,14 @@ func (s *session) createDownload(i *Indexer, displayVersion string, mainJar []by
 	return nil
 }
 
-func (i *Indexer) generateChangelog(p *project, commit string, parentCommit string, require bool) (string, error) {
+func (s *session) generateChangelog(i *Indexer, commit string, parentCommit string, require bool) (string, error) {
 	if commit == parentCommit {
 		// No changes
 		return emptyChangelog, nil
 	}
 
 	// Generate changelog
-	repo, err := i.git.OpenGitHub(p.githubOwner, p.githubRepo)
+	repo, err := i.git.OpenGitHub(s.project.githubOwner, s.project.githubRepo, s.log)
 	if err != nil {
 		return "", httperror.InternalError("Git error (failed to open repository)", err)
 	}
@@ -187,7 +240,7 @@ func (i *Indexer) generateChangelog(p *project, commit string, parentCommit stri
 	changelog, err := repo.GenerateChangelog(commit, parentCommit)
 	if err != nil {
 		if !require {
-			i.Log.Println("Failed to generate changelog:", err)
+			s.log.Warnln("Failed to generate changelog:", err)
 			return "", nil // Ignore error
 		}
 		return "", httperror.InternalError("Git error (failed to generate changelog)", err)
@@ -201,31 +254,36 @@ func (i *Indexer) generateChangelog(p *project, commit string, parentCommit stri
 	return string(jsonBytes), nil
 }
 
-func (a *artifact) create(s *session, t artifactType, data []byte, upload bool) (err error) {
+func (a *artifact) create(s *session, t artifactType, u *upload, index bool) (err error) {
 	a.uploaded = true
 
-	md5SumBytes := md5.Sum(data)
-	md5Sum := hex.EncodeToString(md5SumBytes[:])
+	err = a.setOrVerifyMD5(u.md5)
+	if err != nil {
+		return
+	}
 
-	err = a.setOrVerifyMD5(md5Sum)
+	err = a.setOrVerifySHA1(u.sha1)
 	if err != nil {
 		return
 	}
 
-	sha1SumBytes := sha1.Sum(data)
-	sha1Sum := hex.EncodeToString(sha1SumBytes[:])
+	err = a.setOrVerifySHA256(u.sha256)
+	if err != nil {
+		return
+	}
 
-	err = a.setOrVerifySHA1(sha1Sum)
+	err = a.setOrVerifySHA512(u.sha512)
 	if err != nil {
 		return
 	}
 
-	if !upload {
+	if !index {
 		return
 	}
 
-	_, err = s.tx.Exec("INSERT INTO artifacts VALUES ($1, $2, $3, $4, $5, $6);",
-		s.downloadID, t.classifier, t.extension, len(data), a.sha1, a.md5)
+	_, err = s.tx.Exec("INSERT INTO artifacts (download_id, classifier, extension, size, sha1, md5, sha256, sha512) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8);",
+		s.downloadID, t.classifier, t.extension, u.size, a.sha1, a.md5, a.sha256, a.sha512)
 	if err != nil {
 		return httperror.InternalError("Database error (failed to create artifact)", err)
 	}
@@ -234,20 +292,27 @@ func (a *artifact) create(s *session, t artifactType, data []byte, upload bool)
 }
 
 func (a *artifact) setOrVerifyMD5(md5Sum string) error {
-	if a.md5 == "" {
-		a.md5 = md5Sum
-	} else if a.md5 != md5Sum {
-		return httperror.BadRequest("MD5 checksum mismatch: "+a.md5+" != "+md5Sum, nil)
-	}
-
-	return nil
+	return setOrVerifyChecksum("MD5", &a.md5, md5Sum)
 }
 
 func (a *artifact) setOrVerifySHA1(sha1Sum string) error {
-	if a.sha1 == "" {
-		a.sha1 = sha1Sum
-	} else if a.sha1 != sha1Sum {
-		return httperror.BadRequest("SHA1 checksum mismatch: "+a.sha1+" != "+sha1Sum, nil)
+	return setOrVerifyChecksum("SHA1", &a.sha1, sha1Sum)
+}
+
+func (a *artifact) setOrVerifySHA256(sha256Sum string) error {
+	return setOrVerifyChecksum("SHA256", &a.sha256, sha256Sum)
+}
+
+func (a *artifact) setOrVerifySHA512(sha512Sum string) error {
+	return setOrVerifyChecksum("SHA512", &a.sha512, sha512Sum)
+}
+
+func setOrVerifyChecksum(name string, checksum *string, sum string) error {
+	if *checksum == "" {
+		*checksum = sum
+	} else if *checksum != sum {
+		return httperror.Problem(http.StatusBadRequest, "checksum-mismatch", name+" checksum mismatch: "+*checksum+" != "+sum,
+			httperror.Details{"algorithm": name, "expected": *checksum, "actual": sum})
 	}
 
 	return nil
@@ -2,21 +2,81 @@ package indexer
 
 import (
 	"archive/zip"
-	"bytes"
+	"github.com/SpongePowered/DownloadIndexer/indexer/forge"
 	"github.com/SpongePowered/DownloadIndexer/indexer/jar"
 	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"github.com/SpongePowered/DownloadIndexer/indexer/sponge"
+	"io"
+	"strings"
 	"time"
 )
 
-func readJar(zipBytes []byte, readMeta bool) (m jar.Manifest, manifestTime time.Time, metadata []*mcmod.Metadata, err error) {
-	reader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+// metadataReader reads the plugin metadata from a file in the JAR.
+type metadataReader struct {
+	fileName string
+	read     func(reader io.Reader) ([]*mcmod.Metadata, error)
+}
+
+// metadataReaders contains the supported plugin metadata formats. If a JAR
+// contains multiple metadata files, they are searched for the plugin in the
+// order of the list.
+var metadataReaders = []metadataReader{
+	{sponge.MetadataFileName, sponge.ReadMetadata},
+	{forge.MetadataFileName, forge.ReadMetadata},
+	{mcmod.MetadataFileName, mcmod.ReadMetadata},
+}
+
+// pluginMetadata is the plugin metadata read from a metadata file in the JAR.
+type pluginMetadata struct {
+	fileName string
+	plugins  []*mcmod.Metadata
+	// err is set if the file could not be read
+	err error
+}
+
+// find returns the metadata of the plugin with the given ID, or nil if the file does not contain it.
+func (m *pluginMetadata) find(id string) *mcmod.Metadata {
+	for _, plugin := range m.plugins {
+		if plugin.ID == id {
+			return plugin
+		}
+	}
+
+	return nil
+}
+
+func findMetadataReader(name string) int {
+	for i, r := range metadataReaders {
+		if r.fileName == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func metadataFileNames() string {
+	names := make([]string, len(metadataReaders))
+	for i, r := range metadataReaders {
+		names[i] = r.fileName
+	}
+	return strings.Join(names, ", ")
+}
+
+// readJar reads the manifest and (optionally) all plugin metadata files in the
+// JAR, ordered by the priority of their format.
+func readJar(path string, readMeta bool) (m *jar.Manifest, manifestTime time.Time, metadata []*pluginMetadata, err error) {
+	reader, err := zip.OpenReader(path)
 	if err != nil {
 		return
 	}
 
+	defer reader.Close()
+
+	metadataFiles := make([]*zip.File, len(metadataReaders))
+
 	for _, file := range reader.File {
-		switch file.Name {
-		case jar.ManifestPath:
+		if file.Name == jar.ManifestPath {
 			if file.ModifiedTime != 0 || file.ModifiedDate > 33 {
 				// Modification time is set
 				manifestTime = file.ModTime()
@@ -27,27 +87,30 @@ func readJar(zipBytes []byte, readMeta bool) (m jar.Manifest, manifestTime time.
 				return
 			}
 
-			if !readMeta || metadata != nil {
+			if !readMeta {
 				return
 			}
-		case mcmod.MetadataFileName:
-			if readMeta {
-				metadata, err = readMetadata(file)
-				if err != nil {
-					return
-				}
-
-				if m != nil {
-					return
-				}
+		} else if readMeta {
+			if i := findMetadataReader(file.Name); i != -1 {
+				metadataFiles[i] = file
 			}
 		}
 	}
 
+	for i, file := range metadataFiles {
+		if file == nil {
+			continue
+		}
+
+		// Invalid metadata files are only reported if no other file contains the plugin
+		plugins, readErr := readMetadata(file, metadataReaders[i].read)
+		metadata = append(metadata, &pluginMetadata{fileName: file.Name, plugins: plugins, err: readErr})
+	}
+
 	return
 }
 
-func readManifest(file *zip.File) (jar.Manifest, error) {
+func readManifest(file *zip.File) (*jar.Manifest, error) {
 	reader, err := file.Open()
 	if err != nil {
 		return nil, err
@@ -57,12 +120,12 @@ func readManifest(file *zip.File) (jar.Manifest, error) {
 	return jar.ReadManifest(reader)
 }
 
-func readMetadata(file *zip.File) ([]*mcmod.Metadata, error) {
+func readMetadata(file *zip.File, read func(reader io.Reader) ([]*mcmod.Metadata, error)) ([]*mcmod.Metadata, error) {
 	reader, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
 
 	defer reader.Close()
-	return mcmod.ReadMetadata(reader)
+	return read(reader)
 }
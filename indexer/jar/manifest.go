@@ -4,30 +4,191 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strconv"
 )
 
+// Manifest format, see https://docs.oracle.com/javase/8/docs/technotes/guides/jar/jar.html#JAR_Manifest
+
 const (
 	ManifestPath = "META-INF/MANIFEST.MF"
 	separator    = ':'
+
+	// sectionName is the attribute that starts a per-entry section
+	sectionName = "Name"
+	// maxNameLength is the maximal length of attribute names
+	maxNameLength = 70
 )
 
-type Manifest map[string]string
+// Attributes contains the attributes of a manifest section.
+type Attributes map[string]string
+
+type Manifest struct {
+	// Main contains the main attributes of the manifest
+	Main Attributes
+	// Sections contains the per-entry attributes, by the name of their entry
+	Sections map[string]Attributes
+
+	// Errors contains the lines that were skipped because they do not match the manifest format
+	Errors []*SyntaxError
+}
+
+// SyntaxError describes a line that does not match the manifest format.
+type SyntaxError struct {
+	Line    int
+	Message string
+}
 
-func ReadManifest(reader io.Reader) (Manifest, error) {
-	m := make(Manifest)
+func (e *SyntaxError) Error() string {
+	return "Invalid manifest (line " + strconv.Itoa(e.Line) + "): " + e.Message
+}
+
+// ReadManifest reads the manifest with its main attributes and all per-entry
+// sections. Invalid lines are skipped and reported in Errors, so manifests
+// written by lenient tools can still be read.
+func ReadManifest(reader io.Reader) (*Manifest, error) {
+	m := &Manifest{Main: make(Attributes), Sections: make(map[string]Attributes)}
+
+	// The current section, nil between sections
+	section := m.Main
+	main := true
+
+	var lastKey string
+	lineNumber := 0
 
 	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanLines)
 	for scanner.Scan() {
 		line := scanner.Bytes()
+		lineNumber++
+
+		if len(line) == 0 {
+			// Sections are separated by empty lines
+			if section != nil && !main {
+				m.addSection(section)
+			}
+
+			section, main = nil, false
+			lastKey = ""
+			continue
+		}
+
+		if line[0] == ' ' {
+			// Continuation of the previous value
+			if lastKey == "" {
+				m.Errors = append(m.Errors, &SyntaxError{lineNumber, "Continuation line without attribute"})
+				continue
+			}
+
+			section[lastKey] += string(line[1:])
+			continue
+		}
+
+		// Continuation lines of skipped lines are skipped as well
+		lastKey = ""
+
 		i := bytes.IndexByte(line, separator)
 		if i == -1 {
-			continue // TODO: Warn about invalid lines?
+			m.Errors = append(m.Errors, &SyntaxError{lineNumber, "Missing '" + string(separator) + "' in attribute"})
+			continue
+		}
+
+		key := string(line[:i])
+		if !isValidName(key) {
+			m.Errors = append(m.Errors, &SyntaxError{lineNumber, "Invalid attribute name: " + key})
+			continue
+		}
+
+		// The value is separated with a single space
+		value := line[i+1:]
+		if len(value) > 0 && value[0] == ' ' {
+			value = value[1:]
+		}
+
+		if section == nil {
+			if key != sectionName {
+				m.Errors = append(m.Errors, &SyntaxError{lineNumber, "Section must start with " + sectionName + " attribute"})
+				continue
+			}
+
+			section = make(Attributes)
 		}
 
-		key := string(bytes.TrimSpace(line[:i]))
-		value := string(bytes.TrimSpace(line[i+1:]))
-		m[key] = value
+		section[key] = string(value)
+		lastKey = key
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if section != nil && !main {
+		m.addSection(section)
+	}
+
+	return m, nil
+}
+
+// addSection adds a per-entry section. Multiple sections for the same entry are merged.
+func (m *Manifest) addSection(section Attributes) {
+	name := section[sectionName]
+
+	existing := m.Sections[name]
+	if existing == nil {
+		m.Sections[name] = section
+		return
+	}
+
+	for key, value := range section {
+		existing[key] = value
+	}
+}
+
+func isValidName(name string) bool {
+	if name == "" || len(name) > maxNameLength {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scanLines splits the manifest into lines. Lines can end with CR LF, LF or CR.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+
+		// CR, check if it is followed by LF
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+
+		// Request more data to check for LF
+		return 0, nil, nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
 	}
 
-	return m, scanner.Err()
+	// Request more data
+	return 0, nil, nil
 }
@@ -0,0 +1,107 @@
+package jar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name     string
+		input    string
+		main     Attributes
+		sections map[string]Attributes
+		errors   []int
+	}{
+		{
+			name:  "LF",
+			input: "Manifest-Version: 1.0\nMain-Class: org.example.Main\n",
+			main:  Attributes{"Manifest-Version": "1.0", "Main-Class": "org.example.Main"},
+		},
+		{
+			name:  "CRLF",
+			input: "Manifest-Version: 1.0\r\nMain-Class: org.example.Main\r\n",
+			main:  Attributes{"Manifest-Version": "1.0", "Main-Class": "org.example.Main"},
+		},
+		{
+			name:  "CR",
+			input: "Manifest-Version: 1.0\rMain-Class: org.example.Main\r",
+			main:  Attributes{"Manifest-Version": "1.0", "Main-Class": "org.example.Main"},
+		},
+		{
+			name:  "missing final newline",
+			input: "Manifest-Version: 1.0\nMain-Class: org.example.Main",
+			main:  Attributes{"Manifest-Version": "1.0", "Main-Class": "org.example.Main"},
+		},
+		{
+			name:  "continuation",
+			input: "Class-Path: " + long[:58] + "\r\n " + long + "\r\n b.jar\r\n",
+			main:  Attributes{"Class-Path": long[:58] + long + "b.jar"},
+		},
+		{
+			name: "sections",
+			input: "Manifest-Version: 1.0\n\nName: org/example/\nSealed: true\n\n\n" +
+				"Name: org/example/Main.class\nSHA-256-Digest: abc\n\n",
+			main: Attributes{"Manifest-Version": "1.0"},
+			sections: map[string]Attributes{
+				"org/example/":           {"Name": "org/example/", "Sealed": "true"},
+				"org/example/Main.class": {"Name": "org/example/Main.class", "SHA-256-Digest": "abc"},
+			},
+		},
+		{
+			name:  "duplicate sections",
+			input: "Manifest-Version: 1.0\n\nName: a\nX: 1\n\nName: a\nY: 2\n",
+			main:  Attributes{"Manifest-Version": "1.0"},
+			sections: map[string]Attributes{
+				"a": {"Name": "a", "X": "1", "Y": "2"},
+			},
+		},
+		{
+			name:   "invalid lines",
+			input:  "Manifest-Version: 1.0\ngarbage\n continued\nBad Name: x\nMain-Class: org.example.Main\n",
+			main:   Attributes{"Manifest-Version": "1.0", "Main-Class": "org.example.Main"},
+			errors: []int{2, 3, 4},
+		},
+		{
+			name:  "section without name",
+			input: "Manifest-Version: 1.0\n\nSealed: true\n\nName: a\nX: 1\n",
+			main:  Attributes{"Manifest-Version": "1.0"},
+			sections: map[string]Attributes{
+				"a": {"Name": "a", "X": "1"},
+			},
+			errors: []int{3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := ReadManifest(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(m.Main, test.main) {
+				t.Errorf("main attributes: got %v, expected %v", m.Main, test.main)
+			}
+
+			sections := test.sections
+			if sections == nil {
+				sections = map[string]Attributes{}
+			}
+			if !reflect.DeepEqual(m.Sections, sections) {
+				t.Errorf("sections: got %v, expected %v", m.Sections, sections)
+			}
+
+			var lines []int
+			for _, e := range m.Errors {
+				lines = append(lines, e.Line)
+			}
+			if !reflect.DeepEqual(lines, test.errors) {
+				t.Errorf("errors: got %v, expected lines %v", m.Errors, test.errors)
+			}
+		})
+	}
+}
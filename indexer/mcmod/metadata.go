@@ -11,22 +11,41 @@ const (
 	versionSeparator = '@'
 )
 
+// Load orders of dependencies, relative to the plugin
+const (
+	// LoadAfter loads the plugin after the dependency
+	LoadAfter = "after"
+	// LoadBefore loads the plugin before the dependency
+	LoadBefore = "before"
+)
+
 type Metadata struct {
-	ID string `json:"modid"`
-	//Name    string `json:"name,omitempty"`
+	ID      string `json:"modid"`
+	Name    string `json:"name,omitempty"`
 	Version string `json:"version,omitempty"`
 
-	//Description string `json:"description,omitempty"`
-	//URL         string `json:"url,omitempty"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
 
-	//Authors []string `json:"authors,omitempty"`
+	Authors []string `json:"authorList,omitempty"`
+	// Older versions of the format use "authors" instead of "authorList"
+	LegacyAuthors []string `json:"authors,omitempty"`
 
+	// Dependencies are loaded before the plugin
 	Dependencies []Dependency `json:"dependencies,omitempty"`
+	// RequiredMods must be installed for the plugin to load
+	RequiredMods []Dependency `json:"requiredMods,omitempty"`
+	// Dependants are other mods that depend on the plugin (and are loaded after
+	// it). They are not dependencies of the plugin, so they are not indexed.
+	Dependants []Dependency `json:"dependants,omitempty"`
 }
 
 type Dependency struct {
 	ID      string
 	Version string
+
+	Required  bool
+	LoadOrder string
 }
 
 func (d *Dependency) MarshalText() ([]byte, error) {
@@ -48,12 +67,65 @@ func (d *Dependency) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// normalize merges the required mods into the dependencies, so all dependencies
+// are available with their load order in Dependencies.
+func (m *Metadata) normalize() {
+	if m.Authors == nil {
+		m.Authors = m.LegacyAuthors
+	}
+	m.LegacyAuthors = nil
+
+	index := make(map[string]int)
+	var result []Dependency
+
+	add := func(d Dependency) *Dependency {
+		if i, ok := index[d.ID]; ok {
+			if result[i].Version == "" {
+				result[i].Version = d.Version
+			}
+			return &result[i]
+		}
+
+		index[d.ID] = len(result)
+		result = append(result, d)
+		return &result[len(result)-1]
+	}
+
+	for _, d := range m.Dependencies {
+		d.LoadOrder = LoadAfter
+		add(d)
+	}
+	for _, d := range m.RequiredMods {
+		add(d).Required = true
+	}
+
+	m.Dependencies, m.RequiredMods = result, nil
+}
+
 func ReadMetadataBytes(bytes []byte) ([]*Metadata, error) {
 	var meta []*Metadata
-	return meta, json.Unmarshal(bytes, &meta)
+	err := json.Unmarshal(bytes, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range meta {
+		m.normalize()
+	}
+
+	return meta, nil
 }
 
 func ReadMetadata(reader io.Reader) ([]*Metadata, error) {
 	var meta []*Metadata
-	return meta, json.NewDecoder(reader).Decode(&meta)
+	err := json.NewDecoder(reader).Decode(&meta)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range meta {
+		m.normalize()
+	}
+
+	return meta, nil
 }
@@ -0,0 +1,479 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/pom"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The maven-metadata.xml files are generated from the index, so they always
+// match the downloads (even if several clients publish at the same time).
+// Metadata uploaded by clients is only validated, but never published.
+
+const (
+	metadataModelVersion = "1.1.0"
+	metadataTimeFormat   = "20060102150405"
+	snapshotTimeFormat   = "20060102.150405"
+)
+
+type mavenMetadata struct {
+	XMLName      xml.Name `xml:"metadata"`
+	ModelVersion string   `xml:"modelVersion,attr,omitempty"`
+
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version,omitempty"`
+
+	Versioning struct {
+		Latest  string `xml:"latest,omitempty"`
+		Release string `xml:"release,omitempty"`
+
+		Snapshot *struct {
+			Timestamp   string `xml:"timestamp"`
+			BuildNumber int    `xml:"buildNumber"`
+		} `xml:"snapshot"`
+
+		Versions    []string `xml:"versions>version"`
+		LastUpdated string   `xml:"lastUpdated,omitempty"`
+
+		SnapshotVersions []snapshotVersion `xml:"snapshotVersions>snapshotVersion"`
+	} `xml:"versioning"`
+}
+
+type snapshotVersion struct {
+	Classifier string `xml:"classifier,omitempty"`
+	Extension  string `xml:"extension"`
+	Value      string `xml:"value"`
+	Updated    string `xml:"updated,omitempty"`
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// generateMetadata generates the project metadata or the snapshot metadata if
+// the version is set. The query is run in the transaction of the session (if
+// there is one), so it includes the download of the session.
+func (i *Indexer) generateMetadata(s *session, identifier maven.Identifier, version string) (*mavenMetadata, error) {
+	var q queryer = i.DB
+	if s.tx != nil {
+		q = s.tx
+	}
+
+	if version != "" {
+		return generateSnapshotMetadata(q, s.project.id, identifier, version,
+			func(downloadID int, displayVersion string) ([]artifactType, error) {
+				return i.snapshotFiles(s, q, identifier, version, downloadID, displayVersion)
+			})
+	}
+
+	m, err := generateProjectMetadata(q, s.project.id, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the versions that are not indexed (e.g. published before the project was indexed)
+	existing, err := i.repositoryMetadata(metadataPath(identifier, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		m.merge(existing)
+	}
+
+	if len(m.Versioning.Versions) == 0 {
+		return nil, httperror.NotFound("No versions published yet")
+	}
+
+	return m, nil
+}
+
+// snapshotFiles returns the files of the snapshot build. Only JARs are indexed,
+// so the files are taken from the files staged by the session if possible.
+// Otherwise, they are taken from the existing metadata in the repository, or
+// as last resort from the indexed artifacts.
+func (i *Indexer) snapshotFiles(s *session, q queryer, identifier maven.Identifier, version string,
+	downloadID int, displayVersion string) ([]artifactType, error) {
+
+	if s.downloadID == downloadID {
+		if files := s.stagedArtifacts(); len(files) > 0 {
+			return files, nil
+		}
+	}
+
+	existing, err := i.repositoryMetadata(metadataPath(identifier, version))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []artifactType
+	if existing != nil {
+		for _, v := range existing.Versioning.SnapshotVersions {
+			if v.Value == displayVersion {
+				files = append(files, artifactType{v.Classifier, v.Extension})
+			}
+		}
+
+		if len(files) > 0 {
+			return files, nil
+		}
+	}
+
+	// Every published artifact has a POM, even though it is not indexed
+	files = append(files, artifactType{extension: pom.Extension})
+
+	rows, err := q.Query("SELECT classifier, extension FROM artifacts WHERE download_id = $1 "+
+		"ORDER BY classifier, extension;", downloadID)
+	if err != nil {
+		return nil, httperror.InternalError("Database error (failed to generate metadata)", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var t artifactType
+		err = rows.Scan(&t.classifier, &t.extension)
+		if err != nil {
+			return nil, httperror.InternalError("Database error (failed to read metadata)", err)
+		}
+
+		files = append(files, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, httperror.InternalError("Database error (failed to read metadata)", err)
+	}
+
+	return files, nil
+}
+
+// stagedArtifacts returns the artifacts staged by the session (without checksums and metadata).
+func (s *session) stagedArtifacts() []artifactType {
+	var result []artifactType
+	for _, path := range s.staged {
+		p, err := parsePath(path, true)
+		if err == nil && !p.metadata && p.t == file {
+			result = append(result, p.artifact)
+		}
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].classifier != result[b].classifier {
+			return result[a].classifier < result[b].classifier
+		}
+		return result[a].extension < result[b].extension
+	})
+
+	return result
+}
+
+// repositoryMetadata returns the metadata that was published to the repository
+// or nil if there is none.
+func (i *Indexer) repositoryMetadata(path string) (*mavenMetadata, error) {
+	m, err := downloadMetadata(i.repo, path)
+	if err != nil {
+		if httperror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, httperror.InternalError("Failed to download existing metadata", err)
+	}
+
+	return m, nil
+}
+
+// merge adds the versions of the existing metadata that are missing in the
+// generated metadata. The order of the existing versions is kept.
+func (m *mavenMetadata) merge(existing *mavenMetadata) {
+	seen := make(map[string]bool)
+
+	var versions []string
+	for _, list := range [][]string{existing.Versioning.Versions, m.Versioning.Versions} {
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				versions = append(versions, v)
+			}
+		}
+	}
+
+	m.Versioning.Versions = versions
+
+	if m.Versioning.Latest == "" {
+		m.Versioning.Latest = existing.Versioning.Latest
+	}
+	if m.Versioning.Release == "" {
+		m.Versioning.Release = existing.Versioning.Release
+	}
+	if existing.Versioning.LastUpdated > m.Versioning.LastUpdated {
+		m.Versioning.LastUpdated = existing.Versioning.LastUpdated
+	}
+}
+
+// metadataPath returns the path of the project metadata or the snapshot metadata if the version is set.
+func metadataPath(identifier maven.Identifier, version string) string {
+	path := strings.Replace(identifier.GroupID, ".", "/", -1) + "/" + identifier.ArtifactID + "/"
+	if version != "" {
+		path += version + "/"
+	}
+	return path + mavenMetadataFile
+}
+
+func generateProjectMetadata(q queryer, projectID int, identifier maven.Identifier) (*mavenMetadata, error) {
+	rows, err := q.Query("SELECT coalesce(snapshot_version, version) AS v, bool_or(snapshot_version IS NOT NULL), "+
+		"MAX(published) FROM downloads WHERE project_id = $1 GROUP BY v ORDER BY MIN(published);", projectID)
+	if err != nil {
+		return nil, httperror.InternalError("Database error (failed to generate metadata)", err)
+	}
+
+	defer rows.Close()
+
+	m := &mavenMetadata{
+		ModelVersion: metadataModelVersion,
+		GroupID:      identifier.GroupID,
+		ArtifactID:   identifier.ArtifactID,
+	}
+
+	var latest, release, lastUpdated time.Time
+	for rows.Next() {
+		var version string
+		var snapshot bool
+		var published time.Time
+
+		err = rows.Scan(&version, &snapshot, &published)
+		if err != nil {
+			return nil, httperror.InternalError("Database error (failed to read metadata)", err)
+		}
+
+		m.Versioning.Versions = append(m.Versioning.Versions, version)
+
+		if !published.Before(latest) {
+			latest = published
+			m.Versioning.Latest = version
+		}
+
+		if !snapshot && !published.Before(release) {
+			release = published
+			m.Versioning.Release = version
+		}
+
+		if published.After(lastUpdated) {
+			lastUpdated = published
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, httperror.InternalError("Database error (failed to read metadata)", err)
+	}
+
+	if len(m.Versioning.Versions) > 0 {
+		m.Versioning.LastUpdated = lastUpdated.UTC().Format(metadataTimeFormat)
+	}
+
+	return m, nil
+}
+
+// generateSnapshotMetadata generates the metadata for the latest snapshot build.
+// The files are looked up using the function, since only some of them are indexed.
+func generateSnapshotMetadata(q queryer, projectID int, identifier maven.Identifier, version string,
+	files func(downloadID int, displayVersion string) ([]artifactType, error)) (*mavenMetadata, error) {
+
+	var downloadID int
+	var displayVersion string
+	var published time.Time
+
+	err := q.QueryRow("SELECT download_id, version, published FROM downloads "+
+		"WHERE project_id = $1 AND snapshot_version = $2 ORDER BY published DESC LIMIT 1;",
+		projectID, version).Scan(&downloadID, &displayVersion, &published)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, httperror.NotFound("No snapshots published yet")
+		}
+		return nil, httperror.InternalError("Database error (failed to generate metadata)", err)
+	}
+
+	timestamp, buildNumber, err := parseSnapshotVersion(version, displayVersion)
+	if err != nil {
+		return nil, httperror.InternalError("Invalid snapshot version", err)
+	}
+
+	snapshotFiles, err := files(downloadID, displayVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mavenMetadata{
+		ModelVersion: metadataModelVersion,
+		GroupID:      identifier.GroupID,
+		ArtifactID:   identifier.ArtifactID,
+		Version:      version,
+	}
+
+	m.Versioning.Snapshot = &struct {
+		Timestamp   string `xml:"timestamp"`
+		BuildNumber int    `xml:"buildNumber"`
+	}{timestamp, buildNumber}
+
+	updated := published.UTC().Format(metadataTimeFormat)
+	m.Versioning.LastUpdated = updated
+
+	for _, t := range snapshotFiles {
+		m.Versioning.SnapshotVersions = append(m.Versioning.SnapshotVersions, snapshotVersion{
+			Classifier: t.classifier,
+			Extension:  t.extension,
+			Value:      displayVersion,
+			Updated:    updated,
+		})
+	}
+
+	return m, nil
+}
+
+// parseSnapshotVersion returns the timestamp and build number of a snapshot
+// build, e.g. 1.0-20170101.123456-1 for 1.0-SNAPSHOT.
+func parseSnapshotVersion(version, displayVersion string) (timestamp string, buildNumber int, err error) {
+	suffix := strings.TrimPrefix(displayVersion, version[:len(version)-len(snapshotSuffix)]+"-")
+
+	pos := strings.LastIndexByte(suffix, '-')
+	if pos == -1 {
+		err = errors.New("Invalid snapshot version: " + displayVersion)
+		return
+	}
+
+	timestamp = suffix[:pos]
+	if _, err = time.Parse(snapshotTimeFormat, timestamp); err != nil {
+		return
+	}
+
+	buildNumber, err = strconv.Atoi(suffix[pos+1:])
+	return
+}
+
+func (m *mavenMetadata) marshal() ([]byte, error) {
+	data, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// serveMetadata writes the generated metadata (or its checksum) to the response.
+func (i *Indexer) serveMetadata(s *session, p path, w http.ResponseWriter) error {
+	m, err := i.generateMetadata(s, p.Identifier, p.version)
+	if err != nil {
+		return err
+	}
+
+	data, err := m.marshal()
+	if err != nil {
+		return httperror.InternalError("Failed to generate metadata", err)
+	}
+
+	u, err := i.receive(func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return httperror.InternalError("Failed to generate metadata", err)
+	}
+
+	defer u.remove()
+
+	switch p.t {
+	case md5File:
+		data = []byte(u.md5)
+	case sha1File:
+		data = []byte(u.sha1)
+	case sha256File:
+		data = []byte(u.sha256)
+	case sha512File:
+		data = []byte(u.sha512)
+	default:
+		w.Header().Set("Content-Type", "application/xml")
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// verifyMetadata checks that metadata uploaded by the client belongs to the project.
+// The content is discarded because the metadata is generated by the indexer.
+func verifyMetadata(u *upload, p path) error {
+	data, err := u.bytes()
+	if err != nil {
+		return httperror.InternalError("Failed to read metadata", err)
+	}
+
+	m := new(mavenMetadata)
+	err = xml.Unmarshal(data, m)
+	if err != nil {
+		return httperror.Problem(http.StatusBadRequest, "invalid-metadata", "Failed to parse metadata", nil)
+	}
+
+	if m.GroupID != p.GroupID || m.ArtifactID != p.ArtifactID || (p.version != "" && m.Version != p.version) {
+		return httperror.Problem(http.StatusBadRequest, "invalid-metadata", "Metadata does not match the path",
+			httperror.Details{"groupId": m.GroupID, "artifactId": m.ArtifactID, "version": m.Version})
+	}
+
+	return nil
+}
+
+// stageMetadata generates the metadata in the transaction of the session and
+// stages it together with its checksums.
+func (i *Indexer) stageMetadata(s *session, identifier maven.Identifier, version string) error {
+	m, err := i.generateMetadata(s, identifier, version)
+	if err != nil {
+		return err
+	}
+
+	data, err := m.marshal()
+	if err != nil {
+		return httperror.InternalError("Failed to generate metadata", err)
+	}
+
+	path := metadataPath(identifier, version)
+	u, err := i.stageBytes(s, path, data)
+	if err != nil {
+		return err
+	}
+
+	checksums := map[string]string{
+		md5Extension:    u.md5,
+		sha1Extension:   u.sha1,
+		sha256Extension: u.sha256,
+		sha512Extension: u.sha512,
+	}
+
+	for extension, checksum := range checksums {
+		_, err = i.stageBytes(s, path+extension, []byte(checksum))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Indexer) stageBytes(s *session, path string, data []byte) (*upload, error) {
+	u, err := i.receive(func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, httperror.InternalError("Failed to write metadata", err)
+	}
+
+	defer u.remove()
+	return u, i.stage(s, path, u)
+}
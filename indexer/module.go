@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/gradle"
+	"net/http"
+)
+
+// indexModule stores the variants of the Gradle module metadata uploaded for the download.
+func (s *session) indexModule(p path, u *upload) error {
+	f, err := u.open()
+	if err != nil {
+		return httperror.InternalError("Failed to open uploaded file", err)
+	}
+
+	defer f.Close()
+
+	module, err := gradle.ReadModule(f)
+	if err != nil {
+		return httperror.Problem(http.StatusBadRequest, "invalid-module", "Failed to read Gradle module metadata: "+err.Error(), nil)
+	}
+
+	c := module.Component
+	if c.Group != p.GroupID || c.Module != p.ArtifactID || (c.Version != s.version && c.Version != p.displayVersion) {
+		return httperror.Problem(http.StatusBadRequest, "invalid-module", "Gradle module metadata does not match the path",
+			httperror.Details{"group": c.Group, "module": c.Module, "version": c.Version})
+	}
+
+	for _, v := range module.Variants {
+		if v.Attributes == nil {
+			v.Attributes = make(map[string]interface{})
+		}
+
+		attributes, err := json.Marshal(v.Attributes)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant attributes", err)
+		}
+
+		capabilities, err := marshalOptional(len(v.Capabilities) > 0, v.Capabilities)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant capabilities", err)
+		}
+
+		dependencies, err := marshalOptional(len(v.Dependencies) > 0, v.Dependencies)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant dependencies", err)
+		}
+
+		dependencyConstraints, err := marshalOptional(len(v.DependencyConstraints) > 0, v.DependencyConstraints)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant dependency constraints", err)
+		}
+
+		files, err := marshalOptional(len(v.Files) > 0, v.Files)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant files", err)
+		}
+
+		availableAt, err := marshalOptional(v.AvailableAt != nil, v.AvailableAt)
+		if err != nil {
+			return httperror.InternalError("Failed to serialize variant location", err)
+		}
+
+		_, err = s.tx.Exec("INSERT INTO variants (download_id, name, attributes, capabilities, dependencies, "+
+			"dependency_constraints, files, available_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);",
+			s.downloadID, v.Name, string(attributes), capabilities, dependencies, dependencyConstraints, files, availableAt)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to add variant)", err)
+		}
+	}
+
+	return nil
+}
+
+// marshalOptional returns the JSON of the value or NULL if it is not present.
+func marshalOptional(present bool, v interface{}) (sql.NullString, error) {
+	if !present {
+		return sql.NullString{}, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return db.ToNullString(string(data)), nil
+}
@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/indexer/pom"
+	"net/http"
+)
+
+// indexPOM stores the dependencies and the project metadata of the POM uploaded for the download.
+func (s *session) indexPOM(p path, u *upload) error {
+	f, err := u.open()
+	if err != nil {
+		return httperror.InternalError("Failed to open uploaded file", err)
+	}
+
+	defer f.Close()
+
+	project, err := pom.ReadPOM(f)
+	if err != nil {
+		if _, ok := err.(*pom.PropertyError); ok {
+			// The POM is still published, it just cannot be indexed
+			s.log.Warnln("Skipping indexing of POM:", err)
+			return nil
+		}
+
+		return httperror.Problem(http.StatusBadRequest, "invalid-pom", "Failed to read POM: "+err.Error(), nil)
+	}
+
+	if project.GroupID != p.GroupID || project.ArtifactID != p.ArtifactID ||
+		(project.Version != s.version && project.Version != p.displayVersion) {
+		return httperror.Problem(http.StatusBadRequest, "invalid-pom", "POM does not match the path",
+			httperror.Details{"groupId": project.GroupID, "artifactId": project.ArtifactID, "version": project.Version})
+	}
+
+	licenses, err := marshalOptional(len(project.Licenses) > 0, project.Licenses)
+	if err != nil {
+		return httperror.InternalError("Failed to serialize licenses", err)
+	}
+
+	scm, err := marshalOptional(project.SCM != nil, project.SCM)
+	if err != nil {
+		return httperror.InternalError("Failed to serialize SCM", err)
+	}
+
+	_, err = s.tx.Exec("UPDATE downloads SET description = $1, licenses = $2, scm = $3 WHERE download_id = $4;",
+		db.ToNullString(project.Description), licenses, scm, s.downloadID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update download)", err)
+	}
+
+	for _, d := range project.Dependencies {
+		_, err = s.tx.Exec("INSERT INTO maven_dependencies VALUES ($1, $2, $3, $4, $5, $6, $7, $8) "+
+			"ON CONFLICT DO NOTHING;",
+			s.downloadID, d.GroupID, d.ArtifactID, d.Classifier, d.Type, db.ToNullString(d.Version), d.Scope, d.Optional)
+		if err != nil {
+			return httperror.InternalError("Database error (failed to add Maven dependency)", err)
+		}
+	}
+
+	return nil
+}
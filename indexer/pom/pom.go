@@ -0,0 +1,251 @@
+package pom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	Extension = "pom"
+
+	defaultScope = "compile"
+	defaultType  = "jar"
+
+	// Properties are only resolved a few times, to avoid endless loops with recursive properties
+	maxInterpolations = 8
+)
+
+type Project struct {
+	XMLName xml.Name `xml:"project"`
+	Parent  *Parent  `xml:"parent"`
+
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Packaging  string `xml:"packaging"`
+
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	URL         string `xml:"url"`
+
+	Licenses []*License `xml:"licenses>license"`
+	SCM      *SCM       `xml:"scm"`
+
+	Properties   Properties    `xml:"properties"`
+	Dependencies []*Dependency `xml:"dependencies>dependency"`
+}
+
+type Parent struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+type License struct {
+	Name         string `xml:"name" json:"name,omitempty"`
+	URL          string `xml:"url" json:"url,omitempty"`
+	Distribution string `xml:"distribution" json:"distribution,omitempty"`
+	Comments     string `xml:"comments" json:"comments,omitempty"`
+}
+
+type SCM struct {
+	Connection          string `xml:"connection" json:"connection,omitempty"`
+	DeveloperConnection string `xml:"developerConnection" json:"developerConnection,omitempty"`
+	URL                 string `xml:"url" json:"url,omitempty"`
+	Tag                 string `xml:"tag" json:"tag,omitempty"`
+}
+
+type Dependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	// Version is either a version or a version range (e.g. [1.0,2.0)). It may
+	// be empty if it is managed by a parent POM.
+	Version    string `xml:"version"`
+	Classifier string `xml:"classifier"`
+	Type       string `xml:"type"`
+	Scope      string `xml:"scope"`
+	Optional   bool   `xml:"-"`
+
+	// The optional flag may be set using a property, so it is parsed after the properties were resolved
+	OptionalValue string `xml:"optional"`
+}
+
+// PropertyError is returned if a value required to index the project references
+// a property that cannot be resolved (e.g. from a parent POM) or is invalid.
+type PropertyError struct {
+	Value string
+}
+
+func (e *PropertyError) Error() string {
+	return "Unresolved or invalid value: " + e.Value
+}
+
+// Properties are the custom properties declared in the POM.
+type Properties map[string]string
+
+func (p *Properties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = make(Properties)
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			err = d.DecodeElement(&value, &t)
+			if err != nil {
+				return err
+			}
+
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ReadPOM reads the POM and resolves the properties that are declared in the
+// POM itself. Properties inherited from parent POMs cannot be resolved.
+func ReadPOM(reader io.Reader) (*Project, error) {
+	p := new(Project)
+	err := xml.NewDecoder(reader).Decode(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Parent != nil {
+		p.Parent.GroupID = p.interpolate(p.Parent.GroupID)
+		p.Parent.Version = p.interpolate(p.Parent.Version)
+
+		if p.GroupID == "" {
+			p.GroupID = p.Parent.GroupID
+		}
+		if p.Version == "" {
+			p.Version = p.Parent.Version
+		}
+	}
+
+	// Coordinates may use properties as well, e.g. ${revision} for CI friendly versions
+	p.GroupID = p.interpolate(p.GroupID)
+	p.ArtifactID = p.interpolate(p.ArtifactID)
+	p.Version = p.interpolate(p.Version)
+
+	for _, v := range []string{p.GroupID, p.ArtifactID, p.Version} {
+		if isUnresolved(v) {
+			return nil, &PropertyError{v}
+		}
+	}
+
+	p.Description = p.interpolate(strings.TrimSpace(p.Description))
+
+	for _, d := range p.Dependencies {
+		d.GroupID = p.interpolate(d.GroupID)
+		d.ArtifactID = p.interpolate(d.ArtifactID)
+		d.Version = p.interpolate(d.Version)
+		d.Classifier = p.interpolate(d.Classifier)
+		d.Type = p.interpolate(d.Type)
+		d.Scope = p.interpolate(d.Scope)
+
+		for _, v := range []string{d.GroupID, d.ArtifactID, d.Classifier, d.Type, d.Scope} {
+			if isUnresolved(v) {
+				return nil, &PropertyError{v}
+			}
+		}
+
+		if isUnresolved(d.Version) {
+			// Most likely managed by a parent POM
+			d.Version = ""
+		}
+
+		if optional := p.interpolate(d.OptionalValue); optional != "" {
+			d.Optional, err = strconv.ParseBool(optional)
+			if err != nil {
+				return nil, &PropertyError{d.OptionalValue}
+			}
+		}
+
+		if d.Type == "" {
+			d.Type = defaultType
+		}
+		if d.Scope == "" {
+			d.Scope = defaultScope
+		}
+	}
+
+	return p, nil
+}
+
+func isUnresolved(s string) bool {
+	return strings.Contains(s, "${")
+}
+
+func (p *Project) property(name string) (string, bool) {
+	switch name {
+	case "project.groupId", "pom.groupId", "groupId":
+		return p.GroupID, true
+	case "project.artifactId", "pom.artifactId", "artifactId":
+		return p.ArtifactID, true
+	case "project.version", "pom.version", "version":
+		return p.Version, true
+	case "project.parent.groupId":
+		if p.Parent != nil {
+			return p.Parent.GroupID, true
+		}
+	case "project.parent.version":
+		if p.Parent != nil {
+			return p.Parent.Version, true
+		}
+	}
+
+	value, ok := p.Properties[name]
+	return value, ok
+}
+
+// interpolate replaces the ${...} placeholders with the value of the property.
+// Unknown properties are left as-is.
+func (p *Project) interpolate(s string) string {
+	s = strings.TrimSpace(s)
+
+	for n := 0; n < maxInterpolations && strings.Contains(s, "${"); n++ {
+		replaced := false
+
+		var result bytes.Buffer
+		for {
+			start := strings.Index(s, "${")
+			if start == -1 {
+				break
+			}
+
+			end := strings.IndexByte(s[start:], '}')
+			if end == -1 {
+				break
+			}
+			end += start
+
+			result.WriteString(s[:start])
+			if value, ok := p.property(s[start+2 : end]); ok {
+				result.WriteString(value)
+				replaced = true
+			} else {
+				result.WriteString(s[start : end+1])
+			}
+
+			s = s[end+1:]
+		}
+
+		result.WriteString(s)
+		s = result.String()
+
+		if !replaced {
+			break
+		}
+	}
+
+	return s
+}
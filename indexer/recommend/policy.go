@@ -0,0 +1,115 @@
+// Package recommend implements the policies that decide whether a new build of
+// a project is marked as recommended when it is indexed.
+//
+// Policies are configured per project using a simple string format:
+//
+//	never                 Never recommend builds
+//	always                Recommend all builds
+//	release               Recommend release (non-snapshot) builds
+//	regex:<pattern>       Recommend builds with a version matching the regular expression
+//	manifest:<key>        Recommend builds with the manifest attribute set to "true"
+//	manifest:<key>=<val>  Recommend builds with the manifest attribute set to the value
+//	build-type:<name>     Recommend builds of the build type
+//
+// Any policy can be negated by prefixing it with an exclamation mark (e.g. "!regex:RC").
+package recommend
+
+import (
+	"errors"
+	"github.com/SpongePowered/DownloadIndexer/indexer/jar"
+	"regexp"
+	"strings"
+)
+
+const (
+	Never = "never"
+
+	negation  = '!'
+	separator = ':'
+)
+
+type Build struct {
+	Version   string
+	Snapshot  bool
+	BuildType string
+	Manifest  jar.Attributes // Main attributes of the manifest
+}
+
+type Policy interface {
+	Recommend(b *Build) bool
+}
+
+type policyFunc func(b *Build) bool
+
+func (f policyFunc) Recommend(b *Build) bool {
+	return f(b)
+}
+
+func Parse(s string) (Policy, error) {
+	if s == "" {
+		s = Never
+	}
+
+	if s[0] == negation {
+		p, err := Parse(s[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		return policyFunc(func(b *Build) bool {
+			return !p.Recommend(b)
+		}), nil
+	}
+
+	name, arg := s, ""
+	if pos := strings.IndexByte(s, separator); pos >= 0 {
+		name, arg = s[:pos], s[pos+1:]
+	}
+
+	switch name {
+	case Never:
+		return policyFunc(func(*Build) bool {
+			return false
+		}), nil
+	case "always":
+		return policyFunc(func(*Build) bool {
+			return true
+		}), nil
+	case "release":
+		return policyFunc(func(b *Build) bool {
+			return !b.Snapshot
+		}), nil
+	case "regex":
+		pattern, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return policyFunc(func(b *Build) bool {
+			return pattern.MatchString(b.Version)
+		}), nil
+	case "manifest":
+		if arg == "" {
+			return nil, errors.New("Missing manifest attribute in recommendation policy: " + s)
+		}
+
+		key, value := arg, "true"
+		if pos := strings.IndexByte(arg, '='); pos >= 0 {
+			key, value = arg[:pos], arg[pos+1:]
+		}
+
+		return policyFunc(func(b *Build) bool {
+			return b.Manifest[key] == value
+		}), nil
+	case "build-type":
+		if arg == "" {
+			return nil, errors.New("Missing build type in recommendation policy: " + s)
+		}
+
+		return policyFunc(func(b *Build) bool {
+			return b.BuildType == arg
+		}), nil
+	default:
+		return nil, errors.New("Unknown recommendation policy: " + s)
+	}
+}
@@ -0,0 +1,290 @@
+package indexer
+
+import (
+	"database/sql"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"time"
+)
+
+// Sessions that were not updated for this duration are considered abandoned,
+// e.g. because the instance handling them was restarted.
+const abandonedSessionTimeout = 2 * sessionTimeout
+
+// persistSession stores the session in the database, so it can be recovered
+// if the process is restarted before the session is completed.
+func (i *Indexer) persistSession(p *project, version string) (id int, err error) {
+	err = i.DB.QueryRow("INSERT INTO upload_sessions (project_id, version, instance) VALUES ($1, $2, $3) "+
+		"RETURNING upload_session_id;", p.id, version, i.Instance).Scan(&id)
+	if err != nil {
+		err = httperror.InternalError("Database error (failed to create session)", err)
+	}
+	return
+}
+
+// recordFile remembers the path of a file staged by the session, so it can be
+// published if the process is restarted after the session was committed.
+func (i *Indexer) recordFile(s *session, path string) error {
+	_, err := i.DB.Exec("INSERT INTO upload_session_files (upload_session_id, path) VALUES ($1, $2) "+
+		"ON CONFLICT DO NOTHING;", s.dbID, path)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to record session file)", err)
+	}
+
+	_, err = i.DB.Exec("UPDATE upload_sessions SET updated = current_timestamp WHERE upload_session_id = $1;", s.dbID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update session)", err)
+	}
+
+	return nil
+}
+
+// markCommitted marks the session as committed in the session's transaction,
+// so the state is committed together with the download.
+func (s *session) markCommitted() error {
+	_, err := s.tx.Exec("UPDATE upload_sessions SET committed = TRUE, updated = current_timestamp "+
+		"WHERE upload_session_id = $1;", s.dbID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update session)", err)
+	}
+
+	return nil
+}
+
+// StartSessionRecovery periodically completes or rolls back abandoned sessions,
+// starting with the ones that were left behind when the process was restarted.
+func (i *Indexer) StartSessionRecovery() {
+	go func() {
+		for {
+			err := i.RecoverSessions()
+			if err != nil {
+				i.Log.Errorln("Failed to recover sessions:", err)
+			}
+
+			time.Sleep(sessionTimeout)
+		}
+	}()
+}
+
+// markPublished remembers that the staged file was published, so it is not
+// mistaken for a missing file if publishing is retried.
+func (i *Indexer) markPublished(id int, path string) error {
+	_, err := i.DB.Exec("UPDATE upload_session_files SET published = TRUE WHERE upload_session_id = $1 AND path = $2;",
+		id, path)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update session file)", err)
+	}
+
+	return nil
+}
+
+// isPublished returns true if the staged file was already published.
+func (i *Indexer) isPublished(id int, path string) (published bool, err error) {
+	err = i.DB.QueryRow("SELECT published FROM upload_session_files WHERE upload_session_id = $1 AND path = $2;",
+		id, path).Scan(&published)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+// RecoverSessions completes or rolls back all abandoned sessions of this
+// instance. The staged files are only available on the instance that created
+// the session, so sessions of other instances are left alone. Sessions that
+// are still active are skipped, even if they were not updated for a while
+// (e.g. while a large file is uploaded slowly).
+func (i *Indexer) RecoverSessions() error {
+	rows, err := i.DB.Query("SELECT upload_session_id FROM upload_sessions "+
+		"WHERE updated < $1 AND (instance = $2 OR instance IS NULL);",
+		time.Now().Add(-abandonedSessionTimeout), i.Instance)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	active := i.activeSessions()
+
+	for _, id := range ids {
+		if active[id] {
+			continue
+		}
+
+		i.Log.WithField("session", id).Println("Recovering abandoned session")
+		err = i.finishSession(id)
+		if err != nil {
+			i.Log.WithField("session", id).Errorln("Failed to recover session:", err)
+		}
+	}
+
+	return nil
+}
+
+// activeSessions returns the database IDs of the sessions that are handled by this process.
+func (i *Indexer) activeSessions() map[int]bool {
+	i.sessionLock.RLock()
+	defer i.sessionLock.RUnlock()
+
+	result := make(map[int]bool, len(i.sessions))
+	for _, s := range i.sessions {
+		result[s.dbID] = true
+	}
+	return result
+}
+
+// finishSession removes the persisted session. The staged files of committed
+// sessions are published if that did not happen yet, the staged files of all
+// other sessions are discarded because their transaction was rolled back.
+func (i *Indexer) finishSession(id int) error {
+	tx, err := i.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var projectID int
+	var committed bool
+
+	// Skip sessions that are already handled by another instance
+	err = tx.QueryRow("SELECT project_id, committed FROM upload_sessions "+
+		"WHERE upload_session_id = $1 FOR UPDATE SKIP LOCKED;", id).Scan(&projectID, &committed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if committed {
+		paths, err := sessionFiles(tx, id)
+		if err != nil {
+			return err
+		}
+
+		log := i.Log.WithField("session", id)
+
+		if _, p := i.projectByID(projectID); p != nil {
+			// Publish while holding the project lock, like sessions that are committed normally
+			p.lock.Lock()
+			defer p.lock.Unlock()
+
+			err = i.restageMetadata(&session{dbID: id, log: log, project: p, staged: paths}, paths)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Keep the session if publishing fails so it is retried later
+		err = i.publish(log, id, paths)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = i.discardStaged(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM upload_sessions WHERE upload_session_id = $1;", id)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if committed {
+		i.projectUpdated(projectID)
+	}
+
+	return nil
+}
+
+// restageMetadata generates the staged metadata of a recovered session again.
+// Other sessions might have been committed in the meantime, so the metadata
+// staged by the session could be older than the published metadata. The files
+// are already recorded, so they are only replaced in the staging directory.
+func (i *Indexer) restageMetadata(s *session, paths []string) error {
+	for _, path := range paths {
+		p, err := parsePath(path, false)
+		if err != nil || !p.metadata || p.t != file {
+			continue
+		}
+
+		err = i.stageMetadata(s, p.Identifier, p.version)
+		if err != nil && !httperror.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sessionFiles(tx *sql.Tx, id int) ([]string, error) {
+	rows, err := tx.Query("SELECT path FROM upload_session_files WHERE upload_session_id = $1;", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		err = rows.Scan(&path)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// projectUpdated updates the project timestamp and purges the project from the cache.
+func (i *Indexer) projectUpdated(projectID int) {
+	_, err := i.DB.Exec("UPDATE projects SET last_updated = current_timestamp WHERE project_id = $1;", projectID)
+	if err != nil {
+		i.Log.Errorln("Failed to update project timestamp:", err)
+	}
+
+	if i.Cache == nil {
+		return
+	}
+
+	if identifier, p := i.projectByID(projectID); p != nil {
+		go i.Cache.PurgeProject(identifier)
+	}
+}
+
+// projectByID returns the project with the ID, or nil if it is not configured anymore.
+func (i *Indexer) projectByID(id int) (maven.Identifier, *project) {
+	i.projectsLock.RLock()
+	defer i.projectsLock.RUnlock()
+
+	for identifier, p := range i.projects {
+		if p.id == id {
+			return identifier, p
+		}
+	}
+
+	return maven.Identifier{}, nil
+}
@@ -0,0 +1,103 @@
+package sponge
+
+import (
+	"encoding/json"
+	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"io"
+)
+
+// MetadataFileName is the plugin metadata file used by Sponge API 8 and later
+const MetadataFileName = "META-INF/sponge_plugins.json"
+
+type metadata struct {
+	// Global values are inherited by all plugins in the file
+	Global  plugin    `json:"global"`
+	Plugins []*plugin `json:"plugins"`
+}
+
+type plugin struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+
+	Links struct {
+		Homepage string `json:"homepage"`
+	} `json:"links"`
+
+	Contributors []struct {
+		Name string `json:"name"`
+	} `json:"contributors"`
+
+	Dependencies []dependency `json:"dependencies"`
+}
+
+type dependency struct {
+	ID        string `json:"id"`
+	Version   string `json:"version"`
+	LoadOrder string `json:"load-order"`
+	Optional  bool   `json:"optional"`
+}
+
+// ReadMetadata reads the plugins from a sponge_plugins.json file.
+func ReadMetadata(reader io.Reader) ([]*mcmod.Metadata, error) {
+	var meta metadata
+	err := json.NewDecoder(reader).Decode(&meta)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*mcmod.Metadata, len(meta.Plugins))
+	for i, p := range meta.Plugins {
+		result[i] = &mcmod.Metadata{
+			ID:          p.ID,
+			Name:        p.Name,
+			Version:     p.Version,
+			Description: p.Description,
+			URL:         p.Links.Homepage,
+		}
+
+		if result[i].Version == "" {
+			result[i].Version = meta.Global.Version
+		}
+		if result[i].URL == "" {
+			result[i].URL = meta.Global.Links.Homepage
+		}
+
+		contributors := p.Contributors
+		if contributors == nil {
+			contributors = meta.Global.Contributors
+		}
+
+		for _, c := range contributors {
+			result[i].Authors = append(result[i].Authors, c.Name)
+		}
+
+		dependencies := p.Dependencies
+		if dependencies == nil {
+			dependencies = meta.Global.Dependencies
+		}
+
+		for _, d := range dependencies {
+			result[i].Dependencies = append(result[i].Dependencies, mcmod.Dependency{
+				ID:        d.ID,
+				Version:   d.Version,
+				Required:  !d.Optional,
+				LoadOrder: loadOrder(d.LoadOrder),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func loadOrder(order string) string {
+	switch order {
+	case "after":
+		return mcmod.LoadAfter
+	case "before":
+		return mcmod.LoadBefore
+	default:
+		return "" // undefined
+	}
+}
@@ -0,0 +1,87 @@
+package sponge
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/indexer/mcmod"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+	const input = `{
+  "loader": {"name": "java_plain", "version": "1.0"},
+  "global": {
+    "version": "1.2.0",
+    "links": {"homepage": "https://example.com"},
+    "contributors": [{"name": "Alice"}, {"name": "Bob", "description": "Lead"}],
+    "dependencies": [
+      {"id": "spongeapi", "version": "8.0.0", "load-order": "after", "optional": false}
+    ]
+  },
+  "plugins": [
+    {
+      "id": "example",
+      "name": "Example",
+      "description": "An example plugin"
+    },
+    {
+      "id": "addon",
+      "version": "2.0.0",
+      "links": {"homepage": "https://example.com/addon"},
+      "contributors": [{"name": "Carol"}],
+      "dependencies": [
+        {"id": "example", "version": "[1.2,)", "load-order": "after"},
+        {"id": "other", "load-order": "before", "optional": true},
+        {"id": "any", "load-order": "undefined", "optional": true}
+      ]
+    }
+  ]
+}`
+
+	expected := []*mcmod.Metadata{
+		{
+			ID:          "example",
+			Name:        "Example",
+			Version:     "1.2.0",
+			Description: "An example plugin",
+			URL:         "https://example.com",
+			Authors:     []string{"Alice", "Bob"},
+			Dependencies: []mcmod.Dependency{
+				{ID: "spongeapi", Version: "8.0.0", Required: true, LoadOrder: mcmod.LoadAfter},
+			},
+		},
+		{
+			ID:      "addon",
+			Version: "2.0.0",
+			URL:     "https://example.com/addon",
+			Authors: []string{"Carol"},
+			Dependencies: []mcmod.Dependency{
+				{ID: "example", Version: "[1.2,)", Required: true, LoadOrder: mcmod.LoadAfter},
+				{ID: "other", LoadOrder: mcmod.LoadBefore},
+				{ID: "any"},
+			},
+		},
+	}
+
+	plugins, err := ReadMetadata(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plugins) != len(expected) {
+		t.Fatalf("got %d plugins, expected %d", len(plugins), len(expected))
+	}
+
+	for i, plugin := range plugins {
+		if !reflect.DeepEqual(plugin, expected[i]) {
+			t.Errorf("plugin %d: got %+v, expected %+v", i, plugin, expected[i])
+		}
+	}
+}
+
+func TestReadMetadataInvalid(t *testing.T) {
+	_, err := ReadMetadata(strings.NewReader(`{"plugins": [`))
+	if err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
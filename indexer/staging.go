@@ -0,0 +1,189 @@
+package indexer
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/health"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The files of a session are staged until the session is committed. They are
+// kept outside of the temporary uploads, so committed sessions can be published
+// again when the process is restarted.
+const defaultStagingDir = "spongedownloads-staging"
+
+func (i *Indexer) stagingDir() string {
+	if i.StagingDir != "" {
+		return i.StagingDir
+	}
+	return filepath.Join(os.TempDir(), defaultStagingDir)
+}
+
+func (i *Indexer) sessionDir(id int) string {
+	return filepath.Join(i.stagingDir(), strconv.Itoa(id))
+}
+
+// CheckStagingDir checks that files can be staged in the staging directory.
+func (i *Indexer) CheckStagingDir() error {
+	dir := i.stagingDir()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return health.WritableDir(dir)()
+}
+
+func (i *Indexer) stagedPath(id int, path string) string {
+	return filepath.Join(i.sessionDir(id), filepath.FromSlash(path))
+}
+
+// stage moves the upload to the staging directory of the session. The file is
+// only published to the repository after the session was committed.
+func (i *Indexer) stage(s *session, path string, u *upload) error {
+	// Files that are staged again (e.g. regenerated metadata) are already recorded
+	restaged := s.isStaged(path)
+	if !restaged {
+		err := i.recordFile(s, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	target := i.stagedPath(s.dbID, path)
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return httperror.InternalError("Failed to create staging directory", err)
+	}
+
+	err = u.moveTo(target)
+	if err != nil {
+		return httperror.InternalError("Failed to stage file", err)
+	}
+
+	if !restaged {
+		s.staged = append(s.staged, path)
+	}
+	return nil
+}
+
+func (s *session) isStaged(path string) bool {
+	for _, staged := range s.staged {
+		if staged == path {
+			return true
+		}
+	}
+	return false
+}
+
+// publish uploads all staged files to the repository. Artifacts are published
+// before the metadata, so the metadata never refers to missing artifacts.
+// Published files are removed from the staging directory, so publishing can be
+// retried if it fails.
+func (i *Indexer) publish(log *logrus.Entry, id int, paths []string) error {
+	var metadata []string
+
+	for _, path := range paths {
+		if p, err := parsePath(path, false); err == nil && p.metadata {
+			metadata = append(metadata, path)
+			continue
+		}
+
+		err := i.publishFile(log, id, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range metadata {
+		err := i.publishFile(log, id, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Indexer) publishFile(log *logrus.Entry, id int, path string) error {
+	staged := i.stagedPath(id, path)
+
+	f, err := os.Open(staged)
+	if err != nil {
+		if os.IsNotExist(err) {
+			published, dbErr := i.isPublished(id, path)
+			if dbErr != nil {
+				return httperror.InternalError("Database error (failed to lookup session file)", dbErr)
+			}
+			if published {
+				return nil
+			}
+
+			// Keep the session, the file might be staged on another instance
+			log.WithField("path", path).Errorln("Staged file is missing, cannot publish it")
+		}
+		return httperror.InternalError("Failed to open staged file", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return httperror.InternalError("Failed to open staged file", err)
+	}
+
+	log.WithField("path", path).Debugln("Publishing staged file")
+
+	err = i.repo.Upload(path, f, stat.Size())
+	f.Close()
+	if err != nil {
+		log.WithField("path", path).Errorln("Failed to publish staged file:", err)
+		return err
+	}
+
+	err = i.markPublished(id, path)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(staged)
+}
+
+// discardStaged removes all remaining staged files of the session.
+func (i *Indexer) discardStaged(id int) error {
+	return os.RemoveAll(i.sessionDir(id))
+}
+
+// moveTo moves the upload to the specified path. If the file cannot be renamed
+// (e.g. because the path is on a different file system) it is copied instead,
+// the original file is removed together with the upload.
+func (u *upload) moveTo(path string) error {
+	if os.Rename(u.path, path) == nil {
+		return nil
+	}
+
+	in, err := u.open()
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path)
+	}
+
+	return err
+}
@@ -0,0 +1,106 @@
+package indexer
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// upload is a file received by the indexer. It is stored in a temporary file
+// while its checksums are computed, so it doesn't need to be kept in memory.
+type upload struct {
+	path string
+	size int64
+
+	md5    string
+	sha1   string
+	sha256 string
+	sha512 string
+}
+
+// receive writes the data to a temporary file while computing its checksums.
+func (i *Indexer) receive(write func(w io.Writer) error) (*upload, error) {
+	f, err := ioutil.TempFile(i.TempDir, "upload")
+	if err != nil {
+		return nil, err
+	}
+
+	u := &upload{path: f.Name()}
+
+	md5Hash, sha1Hash, sha256Hash, sha512Hash := md5.New(), sha1.New(), sha256.New(), sha512.New()
+	w := &maven.CountingWriter{Writer: io.MultiWriter(f, md5Hash, sha1Hash, sha256Hash, sha512Hash)}
+
+	err = write(w)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		u.remove()
+		return nil, err
+	}
+
+	u.size = w.N
+	u.md5 = hexSum(md5Hash)
+	u.sha1 = hexSum(sha1Hash)
+	u.sha256 = hexSum(sha256Hash)
+	u.sha512 = hexSum(sha512Hash)
+	return u, nil
+}
+
+// copyBody copies exactly length bytes from the request body and verifies that
+// the body has neither more nor less data than declared by the client. The HTTP
+// server already limits the body to the declared Content-Length, but the body
+// is checked anyway in case it is not read from a request limited like that.
+func copyBody(w io.Writer, body io.Reader, length int64) error {
+	n, err := io.CopyN(w, body, length)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// net/http reports a body that is shorter than declared as unexpected EOF
+		return httperror.Problem(http.StatusBadRequest, "content-length-mismatch",
+			fmt.Sprintf("Request body is shorter than declared Content-Length (received %d of %d bytes)", n, length),
+			httperror.Details{"declared": length, "received": n})
+	}
+	if err != nil {
+		return err
+	}
+
+	var b [1]byte
+	_, err = io.ReadFull(body, b[:])
+	switch err {
+	case nil:
+		return httperror.Problem(http.StatusBadRequest, "content-length-mismatch",
+			fmt.Sprintf("Request body is longer than declared Content-Length (received more than %d bytes)", length),
+			httperror.Details{"declared": length})
+	case io.EOF:
+		return nil
+	default:
+		return err
+	}
+}
+
+func (u *upload) open() (*os.File, error) {
+	return os.Open(u.path)
+}
+
+// bytes reads the complete upload, it should be only used for small files (e.g. checksums).
+func (u *upload) bytes() ([]byte, error) {
+	return ioutil.ReadFile(u.path)
+}
+
+func (u *upload) remove() error {
+	return os.Remove(u.path)
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
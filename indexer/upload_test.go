@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"bytes"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCopyBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   io.Reader
+		length int64
+		valid  bool
+	}{
+		{"exact", strings.NewReader("hello"), 5, true},
+		{"short", strings.NewReader("hell"), 5, false},
+		{"short unexpected EOF", io.MultiReader(strings.NewReader("hell"), errorReader{io.ErrUnexpectedEOF}), 5, false},
+		{"long", strings.NewReader("hello!"), 5, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := copyBody(&buf, test.body, test.length)
+			checkCopyBodyResult(t, err, test.valid)
+		})
+	}
+}
+
+// TestCopyBodyRequest sends bodies that do not match the Content-Length through the HTTP server.
+func TestCopyBodyRequest(t *testing.T) {
+	results := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results <- copyBody(ioutil.Discard, r.Body, 5)
+	}))
+	defer server.Close()
+
+	send := func(body string) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer conn.Close()
+
+		_, err = io.WriteString(conn, "PUT / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\n"+body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body) < 5 {
+			// Close the connection before the complete body was sent
+			conn.(*net.TCPConn).CloseWrite()
+		}
+
+		checkCopyBodyResult(t, <-results, len(body) == 5)
+	}
+
+	t.Run("exact", func(t *testing.T) { send("hello") })
+	t.Run("short", func(t *testing.T) { send("hell") })
+}
+
+func checkCopyBodyResult(t *testing.T, err error, valid bool) {
+	if valid {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	httpErr, ok := err.(*httperror.HTTPError)
+	if !ok || httpErr.Type != "content-length-mismatch" {
+		t.Errorf("expected content-length-mismatch, got %v", err)
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
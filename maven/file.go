@@ -4,22 +4,20 @@ import (
 	"errors"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 )
 
 func createFile(dir *url.URL) (*fileRepository, error) {
-	d, err := os.Stat(dir.Path)
+	repo := &fileRepository{dir.Path}
+	err := repo.Check()
 	if err != nil {
 		return nil, err
 	}
 
-	if !d.IsDir() {
-		return nil, errors.New(dir.Path + " is not a directory")
-	}
-
-	return &fileRepository{dir.Path}, nil
+	return repo, nil
 }
 
 type fileRepository struct {
@@ -68,3 +66,51 @@ func (repo *fileRepository) Upload(path string, reader io.Reader, _ int64) error
 
 	return nil
 }
+
+func (repo *fileRepository) Stat(path string) (*FileInfo, error) {
+	fi, err := os.Stat(repo.dir + path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, httperror.NotFound("File does not exist")
+		}
+
+		return nil, httperror.InternalError("Failed to stat file", err)
+	}
+
+	return fileInfo(fi), nil
+}
+
+func (repo *fileRepository) List(dir string) ([]*FileInfo, error) {
+	fis, err := ioutil.ReadDir(repo.dir + dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, httperror.NotFound("Directory does not exist")
+		}
+
+		return nil, httperror.InternalError("Failed to list directory", err)
+	}
+
+	return fileInfos(fis), nil
+}
+
+func (repo *fileRepository) Delete(path string) error {
+	err := os.Remove(repo.dir + path)
+	if err != nil && !os.IsNotExist(err) {
+		return httperror.InternalError("Failed to delete file", err)
+	}
+
+	return nil
+}
+
+func (repo *fileRepository) Check() error {
+	d, err := os.Stat(repo.dir)
+	if err != nil {
+		return err
+	}
+
+	if !d.IsDir() {
+		return errors.New(repo.dir + " is not a directory")
+	}
+
+	return nil
+}
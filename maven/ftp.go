@@ -63,18 +63,7 @@ func (repo *ftpRepository) Download(path string, writer io.Writer) error {
 		break*/
 	}
 
-	code := http.StatusBadGateway
-
-	if ftpErr, ok := err.(ftpError); ok {
-		switch {
-		case ftpErr.Code() == 550:
-			code = http.StatusNotFound
-		case ftpErr.Timeout():
-			code = http.StatusGatewayTimeout
-		}
-	}
-
-	return httperror.New(code, "Failed to download file", err)
+	return ftpStatusError("Failed to download file", err)
 }
 
 func (repo *ftpRepository) Upload(path string, reader io.Reader, _ int64) error {
@@ -90,6 +79,58 @@ func (repo *ftpRepository) Upload(path string, reader io.Reader, _ int64) error
 	return httperror.New(http.StatusBadGateway, "Failed to upload file", err)
 }
 
+func (repo *ftpRepository) Stat(path string) (*FileInfo, error) {
+	fi, err := repo.ftp.Stat(repo.basePath + path)
+	if err != nil {
+		return nil, ftpStatusError("Failed to stat file", err)
+	}
+
+	return fileInfo(fi), nil
+}
+
+func (repo *ftpRepository) List(dir string) ([]*FileInfo, error) {
+	fis, err := repo.ftp.ReadDir(repo.basePath + dir)
+	if err != nil {
+		return nil, ftpStatusError("Failed to list directory", err)
+	}
+
+	return fileInfos(fis), nil
+}
+
+func (repo *ftpRepository) Delete(path string) error {
+	err := repo.ftp.Delete(repo.basePath + path)
+	if err == nil {
+		return nil
+	}
+
+	if ftpErr, ok := err.(ftpError); ok && ftpErr.Code() == 550 {
+		return nil // File does not exist
+	}
+
+	return httperror.New(http.StatusBadGateway, "Failed to delete file", err)
+}
+
+func (repo *ftpRepository) Check() error {
+	// Connections are established lazily, so send a command to the server
+	_, err := repo.ftp.Getwd()
+	return err
+}
+
+func ftpStatusError(message string, err error) error {
+	code := http.StatusBadGateway
+
+	if ftpErr, ok := err.(ftpError); ok {
+		switch {
+		case ftpErr.Code() == 550:
+			code = http.StatusNotFound
+		case ftpErr.Timeout():
+			code = http.StatusGatewayTimeout
+		}
+	}
+
+	return httperror.New(code, message, err)
+}
+
 func (repo *ftpRepository) createPath(path string) {
 	for i, c := range path {
 		if i > 0 && c == '/' {
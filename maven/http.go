@@ -1,13 +1,37 @@
 package maven
 
 import (
+	"encoding/xml"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 )
 
+const (
+	methodPropfind = "PROPFIND"
+	propfindBody   = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><getcontentlength/><getlastmodified/><resourcetype/></prop></propfind>`
+)
+
+type davMultiStatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		PropStats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ContentLength int64  `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
 func createHTTP(url *url.URL) (*httpRepository, error) {
 	repo := &httpRepository{user: url.User}
 	url.User = nil
@@ -91,3 +115,135 @@ func (repo *httpRepository) Upload(path string, reader io.Reader, len int64) err
 
 	return httperror.New(resp.StatusCode, "Failed to upload file", nil)
 }
+
+func (repo *httpRepository) Stat(path string) (*FileInfo, error) {
+	req, err := repo.prepareRequest(http.MethodHead, repo.downloadURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httperror.New(resp.StatusCode, "Failed to stat file", nil)
+	}
+
+	fi := &FileInfo{Name: baseName(path), Size: resp.ContentLength, Dir: strings.HasSuffix(path, "/")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		fi.Modified = modified
+	}
+
+	return fi, nil
+}
+
+// List lists the directory using WebDAV (PROPFIND).
+func (repo *httpRepository) List(dir string) ([]*FileInfo, error) {
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+
+	req, err := repo.prepareRequest(methodPropfind, repo.url+dir, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, httperror.New(resp.StatusCode, "Failed to list directory", nil)
+	}
+
+	var result davMultiStatus
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, httperror.New(http.StatusBadGateway, "Failed to parse directory listing", err)
+	}
+
+	var fis []*FileInfo
+	for _, r := range result.Responses {
+		href, err := req.URL.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+
+		// The response includes the directory itself
+		if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(req.URL.Path, "/") {
+			continue
+		}
+
+		for _, p := range r.PropStats {
+			if !strings.Contains(p.Status, " 200 ") {
+				continue
+			}
+
+			fi := &FileInfo{
+				Name: baseName(href.Path),
+				Size: p.Prop.ContentLength,
+				Dir:  p.Prop.ResourceType.Collection != nil,
+			}
+			if modified, err := http.ParseTime(p.Prop.LastModified); err == nil {
+				fi.Modified = modified
+			}
+
+			fis = append(fis, fi)
+			break
+		}
+	}
+
+	return fis, nil
+}
+
+func (repo *httpRepository) Delete(path string) error {
+	req, err := repo.prepareRequest(http.MethodDelete, repo.url+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return httperror.New(resp.StatusCode, "Failed to delete file", nil)
+	}
+}
+
+func (repo *httpRepository) Check() error {
+	req, err := repo.prepareRequest(http.MethodHead, repo.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	// Client errors are fine, the repository might not allow requests to the root directory
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return httperror.New(resp.StatusCode, "Repository is unavailable", nil)
+	}
+
+	return nil
+}
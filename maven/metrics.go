@@ -0,0 +1,57 @@
+package maven
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/metrics"
+	"io"
+	"time"
+)
+
+// instrumentedRepository reports the latency and errors of the repository operations.
+type instrumentedRepository struct {
+	Repository
+	scheme string
+}
+
+func (r instrumentedRepository) Download(path string, writer io.Writer) error {
+	start := time.Now()
+	return r.observe("download", start, r.Repository.Download(path, writer))
+}
+
+func (r instrumentedRepository) Upload(path string, reader io.Reader, len int64) error {
+	start := time.Now()
+	return r.observe("upload", start, r.Repository.Upload(path, reader, len))
+}
+
+func (r instrumentedRepository) Stat(path string) (*FileInfo, error) {
+	start := time.Now()
+	fi, err := r.Repository.Stat(path)
+	return fi, r.observe("stat", start, err)
+}
+
+func (r instrumentedRepository) List(dir string) ([]*FileInfo, error) {
+	start := time.Now()
+	fis, err := r.Repository.List(dir)
+	return fis, r.observe("list", start, err)
+}
+
+func (r instrumentedRepository) Delete(path string) error {
+	start := time.Now()
+	return r.observe("delete", start, r.Repository.Delete(path))
+}
+
+func (r instrumentedRepository) Check() error {
+	start := time.Now()
+	return r.observe("check", start, r.Repository.Check())
+}
+
+// observe records the duration of the operation and counts the error if it
+// failed. Missing files are expected (e.g. when checking if a file exists),
+// so they are not counted as errors.
+func (r instrumentedRepository) observe(operation string, start time.Time, err error) error {
+	metrics.RepositoryDuration.WithLabelValues(r.scheme, operation).Observe(metrics.Since(start))
+	if err != nil && !httperror.IsNotFound(err) {
+		metrics.RepositoryErrors.WithLabelValues(r.scheme, operation).Inc()
+	}
+	return err
+}
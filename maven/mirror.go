@@ -0,0 +1,405 @@
+package maven
+
+import (
+	"errors"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mirrorPrefix       = "mirror:"
+	mirrorSeparator    = ","
+	mirrorPolicyOption = ";policy="
+
+	mirrorCheckInterval     = time.Minute
+	mirrorReconcileInterval = 5 * time.Minute
+)
+
+// mirrorPolicy decides which upload failures are reported to the client.
+// Failed uploads to other repositories are retried in the background.
+type mirrorPolicy int
+
+const (
+	// Only failures of the first (primary) repository are fatal
+	mirrorPrimary mirrorPolicy = iota
+	// Failures of any repository are fatal
+	mirrorAll
+	// Only fatal if the upload failed for all repositories
+	mirrorAny
+)
+
+func parseMirrorPolicy(s string) (mirrorPolicy, error) {
+	switch s {
+	case "", "primary":
+		return mirrorPrimary, nil
+	case "all":
+		return mirrorAll, nil
+	case "any":
+		return mirrorAny, nil
+	default:
+		return 0, errors.New("Unsupported mirror policy: " + s)
+	}
+}
+
+// createMirror creates a repository that writes to all repositories in the
+// comma separated list of URLs (mirror:url1,url2,...) and reads from the first
+// healthy one. The policy for failed uploads is configured using an optional
+// ;policy= suffix (e.g. mirror:url1,url2;policy=all). Uploaded files are
+// stored temporarily in UPLOAD_TEMP_DIR (default: system temporary directory).
+func createMirror(config string) (*mirrorRepository, error) {
+	var policyName string
+	if i := strings.LastIndex(config, mirrorPolicyOption); i != -1 {
+		config, policyName = config[:i], config[i+len(mirrorPolicyOption):]
+	}
+
+	policy, err := parseMirrorPolicy(policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := strings.Split(config, mirrorSeparator)
+	if len(urls) < 2 {
+		return nil, errors.New("Mirror requires at least two repositories")
+	}
+
+	repo := &mirrorRepository{
+		policy:  policy,
+		tempDir: os.Getenv("UPLOAD_TEMP_DIR"),
+		log:     logrus.WithField("module", "Mirror"),
+		pending: make(map[string]map[int]bool),
+	}
+
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		child, err := CreateRepository(u)
+		if err != nil {
+			return nil, err
+		}
+
+		repo.children = append(repo.children, &mirrorChild{Repository: child, name: redactURL(u), healthy: true})
+	}
+
+	go repo.run()
+	return repo, nil
+}
+
+type mirrorRepository struct {
+	children []*mirrorChild
+	policy   mirrorPolicy
+	tempDir  string
+	log      *logrus.Entry
+
+	// Files that still need to be uploaded to some of the children (path -> child indices).
+	// They are only kept in memory, so they are lost if the process is restarted.
+	pending     map[string]map[int]bool
+	pendingLock sync.Mutex
+}
+
+type mirrorChild struct {
+	Repository
+	name string // URL without credentials
+
+	healthy     bool
+	healthyLock sync.RWMutex
+}
+
+func (c *mirrorChild) isHealthy() bool {
+	c.healthyLock.RLock()
+	defer c.healthyLock.RUnlock()
+	return c.healthy
+}
+
+func (c *mirrorChild) setHealthy(healthy bool) {
+	c.healthyLock.Lock()
+	defer c.healthyLock.Unlock()
+	c.healthy = healthy
+}
+
+// Download downloads the file from the first healthy repository. Other
+// repositories are only tried if nothing was written yet.
+func (repo *mirrorRepository) Download(path string, writer io.Writer) (err error) {
+	w := &CountingWriter{Writer: writer}
+
+	for _, c := range repo.ordered() {
+		err = c.Download(path, w)
+		if err == nil || w.N > 0 {
+			return
+		}
+
+		if !httperror.IsNotFound(err) {
+			c.setHealthy(false)
+		}
+	}
+
+	return
+}
+
+func (repo *mirrorRepository) Stat(path string) (fi *FileInfo, err error) {
+	err = repo.first(func(c *mirrorChild) (err error) {
+		fi, err = c.Stat(path)
+		return
+	})
+	return
+}
+
+func (repo *mirrorRepository) List(dir string) (fis []*FileInfo, err error) {
+	err = repo.first(func(c *mirrorChild) (err error) {
+		fis, err = c.List(dir)
+		return
+	})
+	return
+}
+
+// first runs the function for the healthy children until it succeeds for one of them.
+func (repo *mirrorRepository) first(f func(c *mirrorChild) error) (err error) {
+	for _, c := range repo.ordered() {
+		err = f(c)
+		if err == nil {
+			return
+		}
+
+		if !httperror.IsNotFound(err) {
+			c.setHealthy(false)
+		}
+	}
+
+	return
+}
+
+// ordered returns the healthy children before the unhealthy ones.
+func (repo *mirrorRepository) ordered() []*mirrorChild {
+	var healthy, unhealthy []*mirrorChild
+	for _, c := range repo.children {
+		if c.isHealthy() {
+			healthy = append(healthy, c)
+		} else {
+			unhealthy = append(unhealthy, c)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// Upload uploads the file to all repositories in parallel. The file is written
+// to a temporary file first because the reader can be only consumed once.
+// Failed uploads are only retried in the background if the upload succeeded
+// according to the policy, otherwise the client is expected to upload the
+// file again.
+func (repo *mirrorRepository) Upload(path string, reader io.Reader, len int64) error {
+	f, err := ioutil.TempFile(repo.tempDir, "mirror")
+	if err != nil {
+		return httperror.InternalError("Failed to create temporary file", err)
+	}
+
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = io.Copy(f, reader)
+	if err != nil {
+		return httperror.InternalError("Failed to write temporary file", err)
+	}
+
+	errs := repo.each(func(c *mirrorChild) error {
+		r, err := os.Open(f.Name())
+		if err != nil {
+			return err
+		}
+
+		defer r.Close()
+		return c.Upload(path, r, len)
+	})
+
+	result := repo.policy.result(errs)
+
+	for i, err := range errs {
+		if err != nil {
+			repo.log.WithField("path", path).Warnln("Failed to upload to", repo.children[i].name+":", err)
+			if result == nil {
+				repo.addPending(path, i)
+			}
+		}
+	}
+
+	return result
+}
+
+func (repo *mirrorRepository) Delete(path string) error {
+	errs := repo.each(func(c *mirrorChild) error {
+		return c.Delete(path)
+	})
+
+	repo.removePending(path)
+	return repo.policy.result(errs)
+}
+
+func (repo *mirrorRepository) Check() error {
+	return repo.policy.result(repo.check())
+}
+
+func (repo *mirrorRepository) check() []error {
+	return repo.each(func(c *mirrorChild) error {
+		err := c.Check()
+		c.setHealthy(err == nil)
+		return err
+	})
+}
+
+// each runs the function for all children in parallel and returns their errors.
+func (repo *mirrorRepository) each(f func(c *mirrorChild) error) []error {
+	errs := make([]error, len(repo.children))
+
+	var wg sync.WaitGroup
+	wg.Add(len(repo.children))
+
+	for i, c := range repo.children {
+		go func(i int, c *mirrorChild) {
+			defer wg.Done()
+			errs[i] = f(c)
+		}(i, c)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func (p mirrorPolicy) result(errs []error) error {
+	switch p {
+	case mirrorPrimary:
+		return errs[0]
+	case mirrorAll:
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	case mirrorAny:
+		for _, err := range errs {
+			if err == nil {
+				return nil
+			}
+		}
+		return errs[0]
+	}
+
+	return nil
+}
+
+func (repo *mirrorRepository) addPending(path string, child int) {
+	repo.pendingLock.Lock()
+	defer repo.pendingLock.Unlock()
+
+	children := repo.pending[path]
+	if children == nil {
+		children = make(map[int]bool)
+		repo.pending[path] = children
+	}
+
+	children[child] = true
+}
+
+func (repo *mirrorRepository) removePending(path string) {
+	repo.pendingLock.Lock()
+	defer repo.pendingLock.Unlock()
+	delete(repo.pending, path)
+}
+
+// run periodically checks the health of the children and uploads the files
+// that could not be uploaded to some of the children again.
+func (repo *mirrorRepository) run() {
+	check := time.NewTicker(mirrorCheckInterval)
+	reconcile := time.NewTicker(mirrorReconcileInterval)
+
+	for {
+		select {
+		case <-check.C:
+			repo.check()
+		case <-reconcile.C:
+			repo.reconcile()
+		}
+	}
+}
+
+func (repo *mirrorRepository) reconcile() {
+	repo.pendingLock.Lock()
+	pending := repo.pending
+	repo.pending = make(map[string]map[int]bool)
+	repo.pendingLock.Unlock()
+
+	for path, children := range pending {
+		for child := range children {
+			err := repo.repush(path, repo.children[child])
+			if err != nil {
+				repo.log.WithField("path", path).Warnln("Failed to upload to", repo.children[child].name,
+					"again:", err)
+				repo.addPending(path, child)
+			} else {
+				repo.log.WithField("path", path).Println("Uploaded to", repo.children[child].name)
+			}
+		}
+	}
+}
+
+// repush downloads the file from a repository that has it and uploads it to the target.
+func (repo *mirrorRepository) repush(path string, target *mirrorChild) error {
+	if !target.isHealthy() {
+		return errors.New("Repository is unhealthy")
+	}
+
+	f, err := ioutil.TempFile(repo.tempDir, "mirror")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = errors.New("No repository has the file")
+	for _, c := range repo.ordered() {
+		if c == target {
+			continue
+		}
+
+		err = c.Download(path, f)
+		if err == nil {
+			break
+		}
+
+		// Discard partially downloaded file
+		f.Truncate(0)
+		f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	return target.Upload(path, f, size)
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "<invalid URL>"
+	}
+
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
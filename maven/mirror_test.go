@@ -0,0 +1,71 @@
+package maven
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+type failingRepository struct {
+	nullRepository
+}
+
+func (failingRepository) Upload(path string, reader io.Reader, len int64) error {
+	return errors.New("upload failed")
+}
+
+func TestMirrorUpload(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  mirrorPolicy
+		fail    bool
+		pending bool
+	}{
+		{"primary", mirrorPrimary, false, true},
+		{"all", mirrorAll, true, false},
+		{"any", mirrorAny, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "mirror")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.RemoveAll(tempDir)
+
+			repo := &mirrorRepository{
+				children: []*mirrorChild{
+					{Repository: nullRepository{}, name: "null", healthy: true},
+					{Repository: failingRepository{}, name: "failing", healthy: true},
+				},
+				policy:  test.policy,
+				tempDir: tempDir,
+				log:     logrus.WithField("module", "Mirror"),
+				pending: make(map[string]map[int]bool),
+			}
+
+			err = repo.Upload("test.jar", strings.NewReader("test"), 4)
+			if (err != nil) != test.fail {
+				t.Errorf("unexpected upload result: %v", err)
+			}
+
+			if repo.pending["test.jar"][1] != test.pending {
+				t.Errorf("expected pending to be %v, got %v", test.pending, repo.pending)
+			}
+
+			files, err := ioutil.ReadDir(tempDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 0 {
+				t.Errorf("expected temporary file to be removed, got %d files", len(files))
+			}
+		})
+	}
+}
@@ -14,3 +14,19 @@ func (r nullRepository) Download(path string, writer io.Writer) error {
 func (r nullRepository) Upload(path string, reader io.Reader, _ int64) error {
 	return nil // Ignore upload
 }
+
+func (r nullRepository) Stat(path string) (*FileInfo, error) {
+	return nil, httperror.NotFound(path + " does not exist")
+}
+
+func (r nullRepository) List(dir string) ([]*FileInfo, error) {
+	return nil, httperror.NotFound(dir + " does not exist")
+}
+
+func (r nullRepository) Delete(path string) error {
+	return nil // Nothing to delete
+}
+
+func (r nullRepository) Check() error {
+	return nil
+}
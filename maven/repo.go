@@ -4,14 +4,57 @@ import (
 	"errors"
 	"io"
 	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
 )
 
 type Repository interface {
 	Download(path string, writer io.Writer) error
 	Upload(path string, reader io.Reader, len int64) error
+
+	// Stat returns information about the file or directory. It returns a 404
+	// httperror if the file does not exist.
+	Stat(path string) (*FileInfo, error)
+	// List returns the files and directories in the directory.
+	List(dir string) ([]*FileInfo, error)
+	// Delete deletes the file. Deleting a file that does not exist is not an error.
+	Delete(path string) error
+
+	// Check verifies that the repository is reachable.
+	Check() error
+}
+
+type FileInfo struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+	Dir      bool
+}
+
+func fileInfo(fi os.FileInfo) *FileInfo {
+	return &FileInfo{Name: fi.Name(), Size: fi.Size(), Modified: fi.ModTime(), Dir: fi.IsDir()}
+}
+
+func baseName(p string) string {
+	return path.Base(strings.TrimSuffix(p, "/"))
+}
+
+func fileInfos(fis []os.FileInfo) []*FileInfo {
+	result := make([]*FileInfo, len(fis))
+	for i, fi := range fis {
+		result[i] = fileInfo(fi)
+	}
+	return result
 }
 
 func CreateRepository(urlString string) (Repository, error) {
+	// The mirror configuration contains other URLs, so it cannot be parsed as URL
+	if strings.HasPrefix(urlString, mirrorPrefix) {
+		return createMirror(urlString[len(mirrorPrefix):])
+	}
+
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return nil, err
@@ -22,6 +65,27 @@ func CreateRepository(urlString string) (Repository, error) {
 		u.Path += "/"
 	}
 
+	repo, err := createRepository(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return instrumentedRepository{repo, u.Scheme}, nil
+}
+
+// CountingWriter counts the bytes written to the underlying writer.
+type CountingWriter struct {
+	io.Writer
+	N int64
+}
+
+func (w *CountingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.N += int64(n)
+	return
+}
+
+func createRepository(u *url.URL) (Repository, error) {
 	switch u.Scheme {
 	case "http", "https":
 		return createHTTP(u)
@@ -29,6 +93,10 @@ func CreateRepository(urlString string) (Repository, error) {
 		return createFile(u)
 	case "ftp":
 		return createFTP(u)
+	case "sftp":
+		return createSFTP(u)
+	case "s3":
+		return createS3(u)
 	case "null":
 		return nullRepository{}, nil
 	default:
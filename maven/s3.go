@@ -0,0 +1,225 @@
+package maven
+
+import (
+	"errors"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/minio/minio-go"
+	"github.com/minio/minio-go/pkg/credentials"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const defaultS3Endpoint = "s3.amazonaws.com"
+
+// createS3 creates a repository for s3://[accessKey:secretKey@]bucket/prefix.
+// The credentials are taken from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// if they are not specified in the URL. See s3Options for the supported query
+// parameters.
+func createS3(url *url.URL) (*s3Repository, error) {
+	bucket := url.Host
+	if bucket == "" {
+		return nil, errors.New("Missing S3 bucket")
+	}
+
+	endpoint, opts, err := s3Options(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.NewWithOptions(endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Repository{client, bucket, strings.TrimPrefix(url.Path, "/")}, nil
+}
+
+// s3Options returns the endpoint and the client options for the URL. The
+// endpoint (default: s3.amazonaws.com), region, path-style addressing (e.g. for
+// MinIO) and HTTP instead of HTTPS are configured using the endpoint, region,
+// pathStyle and insecure query parameters.
+func s3Options(url *url.URL) (string, *minio.Options, error) {
+	query := url.Query()
+
+	endpoint := query.Get("endpoint")
+	if endpoint == "" {
+		endpoint = defaultS3Endpoint
+	}
+
+	opts := &minio.Options{
+		Region: query.Get("region"),
+		Secure: true,
+	}
+
+	if insecure := query.Get("insecure"); insecure != "" {
+		useHTTP, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return "", nil, errors.New("Invalid insecure option: " + insecure)
+		}
+		opts.Secure = !useHTTP
+	}
+
+	if pathStyle := query.Get("pathStyle"); pathStyle != "" {
+		usePathStyle, err := strconv.ParseBool(pathStyle)
+		if err != nil {
+			return "", nil, errors.New("Invalid pathStyle option: " + pathStyle)
+		}
+		if usePathStyle {
+			opts.BucketLookup = minio.BucketLookupPath
+		} else {
+			opts.BucketLookup = minio.BucketLookupDNS
+		}
+	}
+
+	if url.User != nil {
+		secretKey, _ := url.User.Password()
+		opts.Creds = credentials.NewStaticV4(url.User.Username(), secretKey, "")
+	} else {
+		opts.Creds = credentials.NewEnvAWS()
+	}
+
+	return endpoint, opts, nil
+}
+
+type s3Repository struct {
+	s3     *minio.Client
+	bucket string
+	prefix string
+}
+
+func (repo *s3Repository) Download(path string, writer io.Writer) error {
+	obj, err := repo.s3.GetObject(repo.bucket, repo.prefix+path, minio.GetObjectOptions{})
+	if err != nil {
+		return s3Error("Failed to download file", err)
+	}
+
+	defer obj.Close()
+
+	// The object is only requested once it is read
+	_, err = io.Copy(writer, obj)
+	if err != nil {
+		return s3Error("Failed to download file", err)
+	}
+
+	return nil
+}
+
+func (repo *s3Repository) Upload(path string, reader io.Reader, len int64) error {
+	// Files larger than the part size (64 MiB) are uploaded using multipart uploads automatically
+	_, err := repo.s3.PutObject(repo.bucket, repo.prefix+path, reader, len, minio.PutObjectOptions{
+		ContentType: contentType(path),
+	})
+	if err != nil {
+		return s3Error("Failed to upload file", err)
+	}
+
+	return nil
+}
+
+func (repo *s3Repository) Stat(path string) (*FileInfo, error) {
+	info, err := repo.s3.StatObject(repo.bucket, repo.prefix+path, minio.StatObjectOptions{})
+	if err == nil {
+		return &FileInfo{Name: baseName(path), Size: info.Size, Modified: info.LastModified}, nil
+	}
+
+	if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return nil, s3Error("Failed to stat file", err)
+	}
+
+	// Directories only exist implicitly if there are objects with the prefix
+	fis, err := repo.List(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(fis) == 0 {
+		return nil, httperror.NotFound("File does not exist")
+	}
+
+	return &FileInfo{Name: baseName(path), Dir: true}, nil
+}
+
+func (repo *s3Repository) List(dir string) ([]*FileInfo, error) {
+	prefix := repo.prefix + dir
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var result []*FileInfo
+	for obj := range repo.s3.ListObjectsV2(repo.bucket, prefix, false, done) {
+		if obj.Err != nil {
+			return nil, s3Error("Failed to list directory", obj.Err)
+		}
+
+		name := obj.Key[len(prefix):]
+		if strings.HasSuffix(name, "/") {
+			result = append(result, &FileInfo{Name: name[:len(name)-1], Dir: true})
+		} else {
+			result = append(result, &FileInfo{Name: name, Size: obj.Size, Modified: obj.LastModified})
+		}
+	}
+
+	return result, nil
+}
+
+func (repo *s3Repository) Delete(path string) error {
+	// Deleting objects that do not exist is not an error in S3
+	err := repo.s3.RemoveObject(repo.bucket, repo.prefix+path)
+	if err != nil {
+		return s3Error("Failed to delete file", err)
+	}
+
+	return nil
+}
+
+func (repo *s3Repository) Check() error {
+	ok, err := repo.s3.BucketExists(repo.bucket)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.New("Bucket " + repo.bucket + " does not exist")
+	}
+
+	return nil
+}
+
+func s3Error(message string, err error) error {
+	code := http.StatusBadGateway
+
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		code = http.StatusNotFound
+	case "RequestTimeout":
+		code = http.StatusGatewayTimeout
+	}
+
+	return httperror.New(code, message, err)
+}
+
+// contentType returns the content type for the files in a Maven repository.
+func contentType(p string) string {
+	switch ext := path.Ext(p); ext {
+	case ".jar":
+		return "application/java-archive"
+	case ".pom", ".xml":
+		return "application/xml"
+	case ".module", ".json":
+		return "application/json"
+	case ".md5", ".sha1", ".sha256", ".sha512", ".asc":
+		return "text/plain"
+	default:
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,100 @@
+package maven
+
+import (
+	"github.com/minio/minio-go"
+	"net/url"
+	"testing"
+)
+
+func TestCreateS3(t *testing.T) {
+	tests := []struct {
+		url    string
+		bucket string
+		prefix string
+	}{
+		{"s3://maven", "maven", ""},
+		{"s3://maven/", "maven", ""},
+		{"s3://maven/repo", "maven", "repo/"},
+		{"s3://key:secret@maven/releases/repo/?endpoint=localhost:9000", "maven", "releases/repo/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			repo, err := CreateRepository(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s3, ok := repo.(instrumentedRepository).Repository.(*s3Repository)
+			if !ok {
+				t.Fatalf("expected S3 repository, got %T", repo)
+			}
+
+			if s3.bucket != test.bucket || s3.prefix != test.prefix {
+				t.Errorf("expected bucket %q with prefix %q, got bucket %q with prefix %q",
+					test.bucket, test.prefix, s3.bucket, s3.prefix)
+			}
+		})
+	}
+}
+
+func TestCreateS3Invalid(t *testing.T) {
+	tests := []string{
+		"s3:///repo",
+		"s3://maven?insecure=maybe",
+		"s3://maven?pathStyle=maybe",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			_, err := CreateRepository(test)
+			if err == nil {
+				t.Error("expected error for invalid URL")
+			}
+		})
+	}
+}
+
+func TestS3Options(t *testing.T) {
+	tests := []struct {
+		url          string
+		endpoint     string
+		region       string
+		secure       bool
+		bucketLookup minio.BucketLookupType
+	}{
+		{"s3://maven", defaultS3Endpoint, "", true, minio.BucketLookupAuto},
+		{"s3://maven?region=eu-central-1", defaultS3Endpoint, "eu-central-1", true, minio.BucketLookupAuto},
+		{"s3://maven?endpoint=localhost:9000&pathStyle=true&insecure=true", "localhost:9000", "", false,
+			minio.BucketLookupPath},
+		{"s3://maven?endpoint=storage.example.com&pathStyle=false&insecure=false", "storage.example.com", "", true,
+			minio.BucketLookupDNS},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			u, err := url.Parse(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			endpoint, opts, err := s3Options(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if endpoint != test.endpoint {
+				t.Errorf("expected endpoint %q, got %q", test.endpoint, endpoint)
+			}
+			if opts.Region != test.region {
+				t.Errorf("expected region %q, got %q", test.region, opts.Region)
+			}
+			if opts.Secure != test.secure {
+				t.Errorf("expected secure to be %v, got %v", test.secure, opts.Secure)
+			}
+			if opts.BucketLookup != test.bucketLookup {
+				t.Errorf("expected bucket lookup %v, got %v", test.bucketLookup, opts.BucketLookup)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/auth"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/macaron.v1"
+	"os"
+)
+
+func setupMetrics(m *macaron.Macaron) {
+	handler := promhttp.Handler()
+
+	// Authentication is optional because the endpoint is usually only reachable internally
+	if credentials := os.Getenv("METRICS_AUTH"); credentials != "" {
+		m.Get("/metrics", auth.Basic([]byte(credentials)), handler.ServeHTTP)
+	} else {
+		m.Get("/metrics", handler.ServeHTTP)
+	}
+}
@@ -0,0 +1,109 @@
+// Package metrics defines the Prometheus metrics reported by the application.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+const namespace = "spongedownloads"
+
+var (
+	// Indexer
+	ActiveSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "indexer",
+		Name: "active_sessions",
+		Help: "Number of upload sessions that are currently in progress.",
+	})
+	Sessions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "indexer",
+		Name: "sessions_total",
+		Help: "Number of finished upload sessions by outcome (committed, failed, expired).",
+	}, []string{"outcome"})
+	UploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "indexer",
+		Name: "upload_bytes_total",
+		Help: "Number of bytes received by the indexer.",
+	})
+	UploadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "indexer",
+		Name:    "upload_duration_seconds",
+		Help:    "Time spent receiving uploaded files.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+
+	// API
+	APIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "api",
+		Name: "requests_total",
+		Help: "Number of API requests by route and status code (304 if the client cache was still valid).",
+	}, []string{"route", "code"})
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "api",
+		Name:    "request_duration_seconds",
+		Help:    "Latency of API requests by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+
+	// Git
+	GitOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "git",
+		Name: "operations_total",
+		Help: "Number of Git clone and fetch operations by result.",
+	}, []string{"operation", "result"})
+	ChangelogDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "git",
+		Name:    "changelog_duration_seconds",
+		Help:    "Time spent generating changelogs.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// Maven repositories
+	RepositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "repository",
+		Name:    "operation_duration_seconds",
+		Help:    "Latency of Maven repository operations by scheme and operation.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"scheme", "operation"})
+	RepositoryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "repository",
+		Name: "errors_total",
+		Help: "Number of failed Maven repository operations by scheme and operation (excluding missing files).",
+	}, []string{"scheme", "operation"})
+
+	// Cache
+	CachePurges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "cache",
+		Name: "purges_total",
+		Help: "Number of cache purges by type (all, project) and result.",
+	}, []string{"type", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(ActiveSessions, Sessions, UploadBytes, UploadDuration,
+		APIRequests, APIRequestDuration,
+		GitOperations, ChangelogDuration,
+		RepositoryDuration, RepositoryErrors,
+		CachePurges)
+}
+
+// Since returns the number of seconds since the specified time.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Result returns the result label for an operation that returned the specified error.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// SuccessResult returns the result label for an operation that reported whether it was successful.
+func SuccessResult(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/indexer/recommend"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func setupProjects(manager *downloads.Manager) {
+	path := os.Getenv("PROJECTS_CONFIG")
+	if path == "" {
+		return
+	}
+
+	err := applyProjects(manager, path)
+	if err != nil {
+		logger.Fatalln("Failed to apply project configuration:", err)
+	}
+}
+
+func applyProjects(manager *downloads.Manager, path string) error {
+	logger.Println("Applying project configuration from", path)
+
+	config, err := db.LoadConfig(path, validateRecommendation)
+	if err != nil {
+		return err
+	}
+
+	changes, err := db.Reconcile(manager.DB, config, true)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		logger.Println(change)
+	}
+
+	if len(changes) > 0 && manager.Cache != nil {
+		go manager.Cache.PurgeAll()
+	}
+
+	return nil
+}
+
+// validateRecommendation checks that the recommendation policy of a project is supported.
+func validateRecommendation(policy string) error {
+	_, err := recommend.Parse(policy)
+	return err
+}
+
+// reloadProjectsOnSignal re-applies the project configuration and calls the reload
+// functions of the enabled modules when the process receives SIGHUP.
+func reloadProjectsOnSignal(manager *downloads.Manager, reload ...func() error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			logger.Println("Reloading projects")
+
+			if path := os.Getenv("PROJECTS_CONFIG"); path != "" {
+				err := applyProjects(manager, path)
+				if err != nil {
+					logger.Errorln("Failed to apply project configuration:", err)
+					continue
+				}
+			}
+
+			for _, f := range reload {
+				err := f()
+				if err != nil {
+					logger.Errorln("Failed to reload projects:", err)
+				}
+			}
+		}
+	}()
+}
+
+func projectsCommand(args []string) bool {
+	if len(args) != 2 || (args[0] != "diff" && args[0] != "apply") {
+		return false
+	}
+
+	config, err := db.LoadConfig(args[1], validateRecommendation)
+	if err != nil {
+		logger.Fatalln("Failed to load project configuration:", err)
+	}
+
+	postgresDB := connectDatabase()
+	defer postgresDB.Close()
+
+	changes, err := db.Reconcile(postgresDB, config, args[0] == "apply")
+	if err != nil {
+		logger.Fatalln("Failed to reconcile project configuration:", err)
+	}
+
+	if len(changes) == 0 {
+		logger.Println("Project configuration is up-to-date")
+	}
+
+	for _, change := range changes {
+		logger.Println(change)
+	}
+
+	return true
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/promote"
+	"gopkg.in/macaron.v1"
+)
+
+func setupPromote(manager *downloads.Manager, m *macaron.Macaron) {
+	authHandler := setupAuthentication("PROMOTE_AUTH")
+	promote.Create(manager).Setup(m, authHandler)
+}
@@ -0,0 +1,114 @@
+package promote
+
+import (
+	"database/sql"
+	"github.com/SpongePowered/DownloadIndexer/db"
+	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/httperror"
+	"github.com/SpongePowered/DownloadIndexer/maven"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/macaron.v1"
+	"net/http"
+)
+
+type Promoter struct {
+	*downloads.Module
+}
+
+func Create(m *downloads.Manager) *Promoter {
+	return &Promoter{Module: m.Module("Promote")}
+}
+
+func (p *Promoter) Setup(m *macaron.Macaron, auth macaron.Handler) {
+	m.Group("/promote/:groupId/:artifactId", func() {
+		m.Post("/:version", p.Promote)
+		m.Delete("/:version", p.Demote)
+	},
+		p.InitializeContext,
+		httperror.PreferProblem,
+		macaron.Recovery(),
+		auth,
+		downloads.ParseIdentifier)
+}
+
+func (p *Promoter) Promote(ctx *macaron.Context, project maven.Identifier, log *logrus.Entry) error {
+	return p.setRecommended(log, project, ctx.Params("version"), true)
+}
+
+func (p *Promoter) Demote(ctx *macaron.Context, project maven.Identifier, log *logrus.Entry) error {
+	return p.setRecommended(log, project, ctx.Params("version"), false)
+}
+
+func (p *Promoter) setRecommended(log *logrus.Entry, project maven.Identifier, version string, recommended bool) error {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return httperror.InternalError("Database error (failed to start transaction)", err)
+	}
+
+	defer tx.Rollback()
+
+	var projectID, downloadID int
+	var label sql.NullString
+	var allowsPromotion, yanked bool
+
+	err = tx.QueryRow("SELECT project_id, download_id, label, allows_promotion, yanked FROM downloads "+
+		"JOIN projects USING(project_id) JOIN build_types USING(build_type_id) "+
+		"WHERE group_id = $1 AND artifact_id = $2 AND version = $3 FOR UPDATE OF downloads;",
+		project.GroupID, project.ArtifactID, version).Scan(&projectID, &downloadID, &label, &allowsPromotion,
+		&yanked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return httperror.Problem(http.StatusNotFound, "unknown-version", "Unknown version", nil)
+		}
+		return httperror.InternalError("Database error (failed to lookup download)", err)
+	}
+
+	if recommended {
+		if !allowsPromotion {
+			return httperror.BadRequest("Build type does not allow promotion", nil)
+		}
+
+		if yanked {
+			return httperror.BadRequest("Cannot promote yanked download", nil)
+		}
+
+		if label.String == downloads.RecommendedLabel {
+			return nil // Already recommended
+		}
+
+		label = db.ToNullString(downloads.RecommendedLabel)
+	} else {
+		if label.String != downloads.RecommendedLabel {
+			return nil // Not recommended, don't touch other labels
+		}
+
+		label = sql.NullString{}
+	}
+
+	_, err = tx.Exec("UPDATE downloads SET label = $1 WHERE download_id = $2;", label, downloadID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update label)", err)
+	}
+
+	_, err = tx.Exec("UPDATE projects SET last_updated = current_timestamp WHERE project_id = $1;", projectID)
+	if err != nil {
+		return httperror.InternalError("Database error (failed to update project timestamp)", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return httperror.InternalError("Database error (failed to commit transaction)", err)
+	}
+
+	if recommended {
+		log.Println("Promoted", project.GroupID+":"+project.ArtifactID, version)
+	} else {
+		log.Println("Demoted", project.GroupID+":"+project.ArtifactID, version)
+	}
+
+	if p.Cache != nil {
+		go p.Cache.PurgeProject(project)
+	}
+
+	return nil
+}
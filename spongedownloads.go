@@ -2,10 +2,12 @@ package main // import "github.com/SpongePowered/DownloadIndexer"
 
 import (
 	"database/sql"
+	"github.com/SpongePowered/DownloadIndexer/admin"
 	"github.com/SpongePowered/DownloadIndexer/auth"
 	"github.com/SpongePowered/DownloadIndexer/cache"
 	"github.com/SpongePowered/DownloadIndexer/db"
 	"github.com/SpongePowered/DownloadIndexer/downloads"
+	"github.com/SpongePowered/DownloadIndexer/health"
 	"github.com/SpongePowered/DownloadIndexer/httperror"
 	"github.com/SpongePowered/SpongeWebGo"
 	"gopkg.in/macaron.v1"
@@ -17,24 +19,25 @@ import (
 var logger = downloads.CreateLogger("Main")
 
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
 	// Parse module configuration
-	enableIndexer, enableAPI, enablePromote := true, true, true
+	// Admin and metrics are opt-in, they expose destructive or internal endpoints
+	enableIndexer, enableAPI, enablePromote, enableAdmin, enableMetrics := true, true, true, false, false
 
 	if modules := parseModules("MODULES"); modules != nil {
 		logger.Println("Enabled modules:", modules)
 		enableIndexer = modules.isEnabled("indexer")
 		enableAPI = modules.isEnabled("api")
 		enablePromote = modules.isEnabled("promote")
+		enableAdmin = modules.isEnabled("admin")
+		enableMetrics = modules.isEnabled("metrics")
 	}
 
-	var c cache.Cache
-	if cacheConfig := os.Getenv("CACHE"); cacheConfig != "" {
-		var err error
-		c, err = cache.Create(downloads.CreateLogger("Cache"), cacheConfig)
-		if err != nil {
-			logger.Fatalln(err)
-		}
-	}
+	c := setupCache()
 
 	// Setup database and create manager
 	manager := &downloads.Manager{
@@ -42,9 +45,12 @@ func main() {
 		Cache: c,
 	}
 
+	setupProjects(manager)
+
 	// Initialize web framework
 	m := macaron.New()
 	m.Map(httperror.Handler())
+	m.Map(downloads.CreateStdLogger("Macaron"))
 
 	// Setup logging handler
 	if c != nil {
@@ -64,20 +70,48 @@ func main() {
 		})
 	}
 
+	var reload []func() error
+	h := new(health.Health)
+	databaseCheck := health.Database(manager.DB)
+
+	// Deleting downloads requires the indexer, since it removes the files from the repository
+	var deleter admin.VersionDeleter
+
 	if enableIndexer {
 		logger.Println("Starting indexer")
-		setupIndexer(manager, m)
+		i := setupIndexer(manager, m, h)
+		h.Register("indexer", "database", databaseCheck)
+		reload = append(reload, i.LoadProjects)
+		deleter = i
 	}
 
 	if enableAPI {
 		logger.Println("Starting API")
 		setupAPI(manager, m, renderer)
+		h.Register("api", "database", databaseCheck)
 	}
 
 	if enablePromote {
-		// TODO
+		logger.Println("Starting promotion API")
+		setupPromote(manager, m)
+		h.Register("promote", "database", databaseCheck)
+	}
+
+	if enableAdmin {
+		logger.Println("Starting admin API")
+		setupAdmin(manager, m, deleter)
+		h.Register("admin", "database", databaseCheck)
+	}
+
+	h.Setup(m, renderer)
+
+	if enableMetrics {
+		logger.Println("Starting metrics endpoint")
+		setupMetrics(m)
 	}
 
+	reloadProjectsOnSignal(manager, reload...)
+
 	m.Run()
 }
 
@@ -85,7 +119,27 @@ func addHeaders(resp http.ResponseWriter) {
 	resp.Header().Add("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
 }
 
+func setupCache() cache.Cache {
+	cacheConfig := os.Getenv("CACHE")
+	if cacheConfig == "" {
+		return nil
+	}
+
+	c, err := cache.Create(downloads.CreateStdLogger("Cache"), cacheConfig)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	return c
+}
+
 func setupDatabase() *sql.DB {
+	postgresDB := connectDatabase()
+	migrateDatabase(postgresDB)
+	return postgresDB
+}
+
+func connectDatabase() *sql.DB {
 	logger.Println("Connecting to database")
 
 	postgresDB, err := db.ConnectPostgres(requireEnv("POSTGRES_URL"))
@@ -93,13 +147,19 @@ func setupDatabase() *sql.DB {
 		logger.Fatalln(err)
 	}
 
-	// TODO
-	/*err = db.Reset(postgresDB)
+	return postgresDB
+}
+
+func migrateDatabase(postgresDB *sql.DB) {
+	applied, err := db.Migrate(postgresDB)
+	for _, m := range applied {
+		logger.Println("Applied migration", m.Version, "-", m.Description)
+	}
 	if err != nil {
-		logger.Fatalln(err)
-	}*/
+		logger.Fatalln("Failed to migrate database:", err)
+	}
 
-	return postgresDB
+	logger.Println("Database schema is at version", db.LatestSchemaVersion())
 }
 
 func setupAuthentication(key string) macaron.Handler {